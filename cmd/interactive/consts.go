@@ -4,3 +4,5 @@ import "github.com/gdamore/tcell"
 
 var highlighedStyle tcell.Style = tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorBlack)
 var normalStyle tcell.Style = tcell.StyleDefault
+var errorStyle tcell.Style = tcell.StyleDefault.Background(tcell.ColorRed).Foreground(tcell.ColorWhite)
+var warningStyle tcell.Style = tcell.StyleDefault.Foreground(tcell.ColorYellow)
@@ -6,36 +6,80 @@ import (
 	"github.com/urfave/cli/v2"
 
 	"github.com/Ashish-Bansal/redis-spectacles/internal/consts"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/flags"
 	"github.com/Ashish-Bansal/redis-spectacles/internal/redisscanner"
-	"github.com/Ashish-Bansal/redis-spectacles/pkg/trie"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/utils"
 )
 
 // ExecuteInteractive runs interactive version of redis analyzer
 func ExecuteInteractive(c *cli.Context) {
-	redisURL := c.String(consts.RedisURLArgName)
-	scanBatchSize := c.Int64(consts.ScanBatchSizeArgName)
-	scanPattern := c.String(consts.ScanPattern)
+	connectionOptions := flags.GetConnectionOptions(c)
+	scanOptions := flags.GetScanOptions(c)
+	ruleSet, err := flags.GetRuleSet(c, &scanOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := flags.ValidateScanOptions(c, scanOptions); err != nil {
+		log.Fatal(err)
+	}
+	tokenizer, err := flags.GetTokenizer(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	formatKey, err := flags.GetKeyFormatter(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	shards, err := redisscanner.GetShards(connectionOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	client, err := redisscanner.GetRedisClient(redisURL)
+	node, checkpointer, err := redisscanner.PrepareCheckpointing(
+		shards,
+		c.String(consts.CheckpointFileArgName),
+		c.Duration(consts.CheckpointIntervalArgName),
+		c.Bool(consts.ResumeArgName),
+		flags.GetCheckpointSettings(c, scanOptions),
+	)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	keyReceiver := make(chan string)
-	go redisscanner.ScanRedisKeys(client, scanPattern, scanBatchSize, keyReceiver)
+	redisscanner.FetchDBSizes(shards)
+	tracker := redisscanner.NewProgressTracker(shards, scanOptions)
 
+	ctx, cancel := utils.NewInterruptibleContext(c.Duration(consts.MaxDurationArgName))
 	screen := initScreen()
-	node := trie.NewNode()
-	keysScanned := 0
-	for key := range keyReceiver {
-		node.Insert(key)
-		screen.Clear()
-		keysScanned++
-		displayKeysScannedMessage(screen, keysScanned)
-	}
-	node.Condense()
-	screen.Clear()
+	screenState := initScreenState(screen, shards, scanOptions, ruleSet, formatKey, cancel)
+
+	keyReceiver := make(chan redisscanner.KeyRecord)
+	redisscanner.ScanShards(ctx, shards, scanOptions, keyReceiver)
+
+	// Keys are collected in background, so that the trie can be browsed while it's being built.
+	go func() {
+		collectKeys(screen, node, tokenizer, keyReceiver, checkpointer, tracker)
+		interruption := utils.DescribeInterruption(ctx)
+		cancel()
+		checkpointer.Save()
+
+		errorMessages := redisscanner.DescribeScanErrors(shards, scanOptions)
+		if err := checkpointer.Err(); err != nil {
+			errorMessages = append(errorMessages, err.Error())
+		}
+
+		// Trie isn't modified anymore, so it's shown without cloning.
+		node.Condense()
+		event := &snapshotEvent{
+			node:          node,
+			completed:     true,
+			interruption:  interruption,
+			errorMessages: errorMessages,
+		}
+		event.SetEventNow()
+		screen.PostEventWait(event)
+	}()
 
-	screenState := initScreenState(screen, node)
 	startEventLoop(screenState)
 }
@@ -1,13 +1,101 @@
 package interactive
 
 import (
-	"fmt"
+	"time"
 
 	"github.com/gdamore/tcell"
+
+	"github.com/Ashish-Bansal/redis-spectacles/internal/redisscanner"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/iterator"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/trie"
+)
+
+const (
+	progressRefreshInterval = 250 * time.Millisecond
+
+	// snapshotRefreshInterval is longer than progressRefreshInterval, since cloning the trie takes time
+	// proportional to number of keys scanned so far.
+	snapshotRefreshInterval = 2 * time.Second
+
+	// snapshotCostFactor is how many times longer than building the last snapshot the scan runs before
+	// the next one, so that keys are inserted most of the time even once the trie gets large.
+	snapshotCostFactor = 4
 )
 
-func displayKeysScannedMessage(screen tcell.Screen, keysScanned int) {
-	message := fmt.Sprintf("Scanned %d keys...", keysScanned)
-	setStringInScreen(screen, 0, 0, message, tcell.StyleDefault)
-	screen.Show()
+// snapshotEvent carries condensed copy of the trie, so that it can be browsed while the scan keeps
+// inserting keys into the original one.
+type snapshotEvent struct {
+	tcell.EventTime
+	node *trie.Node
+
+	// completed is whether the scan has finished, in which case the interruption and errors are known.
+	completed     bool
+	interruption  string
+	errorMessages []string
+}
+
+// progressEvent carries progress of the scan.
+type progressEvent struct {
+	tcell.EventTime
+	progress redisscanner.Progress
+}
+
+func newSnapshotEvent(node *trie.Node) *snapshotEvent {
+	snapshot := node.Clone()
+	snapshot.Condense()
+
+	event := &snapshotEvent{node: snapshot}
+	event.SetEventNow()
+	return event
+}
+
+// nextSnapshotDelay returns how long to wait before the next snapshot given time spent building the last one.
+func nextSnapshotDelay(snapshotCost time.Duration) time.Duration {
+	delay := snapshotCost * snapshotCostFactor
+	if delay < snapshotRefreshInterval {
+		return snapshotRefreshInterval
+	}
+	return delay
+}
+
+func newProgressEvent(progress redisscanner.Progress) *progressEvent {
+	event := &progressEvent{progress: progress}
+	event.SetEventNow()
+	return event
+}
+
+// collectKeys inserts the scanned keys into the trie until the scan stops. Screen is refreshed periodically,
+// since redrawing it for every key slows down the scan. Snapshots get rarer as they take longer to build,
+// since keys aren't inserted meanwhile. Events are dropped if the event queue is full,
+// newer ones replace them shortly anyway.
+func collectKeys(
+	screen tcell.Screen,
+	node *trie.Node,
+	tokenizer iterator.Tokenizer,
+	keyReceiver <-chan redisscanner.KeyRecord,
+	checkpointer *redisscanner.Checkpointer,
+	tracker *redisscanner.ProgressTracker,
+) {
+	progressTicker := time.NewTicker(progressRefreshInterval)
+	defer progressTicker.Stop()
+	snapshotTimer := time.NewTimer(snapshotRefreshInterval)
+	defer snapshotTimer.Stop()
+
+	screen.PostEvent(newProgressEvent(tracker.Progress()))
+	for {
+		select {
+		case record, ok := <-keyReceiver:
+			if !ok {
+				return
+			}
+			node.InsertWithValue(record.Tokenize(tokenizer), record.Value())
+			checkpointer.Track(record)
+		case <-progressTicker.C:
+			screen.PostEvent(newProgressEvent(tracker.Progress()))
+		case <-snapshotTimer.C:
+			startTime := time.Now()
+			screen.PostEvent(newSnapshotEvent(node))
+			snapshotTimer.Reset(nextSnapshotDelay(time.Since(startTime)))
+		}
+	}
 }
@@ -1,6 +1,7 @@
 package interactive
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sort"
@@ -10,6 +11,10 @@ import (
 	"container/list"
 
 	"github.com/Ashish-Bansal/redis-spectacles/internal/consts"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/keystats"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/redisscanner"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/rules"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/utils"
 	"github.com/Ashish-Bansal/redis-spectacles/pkg/trie"
 	"github.com/gdamore/tcell"
 )
@@ -20,6 +25,8 @@ type ScreenRow struct {
 	Style       tcell.Style
 	PaddingLeft int
 	Metadata    interface{}
+	// Prefix is the key prefix represented by the body row, it's used to keep the row selected across snapshots.
+	Prefix string
 }
 
 // ScreenState represents screen state based off trie
@@ -30,6 +37,30 @@ type ScreenState struct {
 	CurrentBodyRow int
 	Screen         tcell.Screen
 	NodeStack      *list.List
+	ShowMemory     bool
+	ShowTypes      bool
+	ShowTTL        bool
+	ShowObject     bool
+	SortByMemory   bool
+
+	// Estimator scales counts of the sampled keys, it's nil when every key was scanned.
+	Estimator *redisscanner.Estimator
+
+	// RuleSet classifies the keys, it's nil when no rules were given.
+	RuleSet *rules.RuleSet
+
+	// FormatKey formats prefixes for display, so that binary keys don't garble the screen.
+	FormatKey utils.KeyFormatter
+
+	Shards        []*redisscanner.Shard
+	ScanOptions   redisscanner.ScanOptions
+	ErrorMessages []string
+	Interruption  string
+
+	// Scanning is whether keys are still being scanned, in which case trie is a snapshot of the keys scanned so far.
+	Scanning   bool
+	Progress   redisscanner.Progress
+	CancelScan context.CancelFunc
 }
 
 func renderScreenRow(screen tcell.Screen, column int, row int, screenRow ScreenRow) {
@@ -55,11 +86,18 @@ func (screenState *ScreenState) render() {
 	}
 
 	if len(screenState.Body) != 0 {
-		setRowBackground(screen, len(screenState.Header), highlighedStyle)
+		setRowBackground(screen, headerLength+screenState.CurrentBodyRow, highlighedStyle)
 	}
 
 	_, height := screen.Size()
 	footerLength := len(screenState.Footer)
+	details := getSelectedRowDetails(screenState)
+	for index, screenRow := range details {
+		row := height + index - footerLength - len(details)
+		renderScreenRow(screen, 0, row, screenRow)
+		setRowBackground(screen, row, screenRow.Style)
+	}
+
 	for index, screenRow := range screenState.Footer {
 		row := height + index - footerLength
 		renderScreenRow(screen, 0, row, screenRow)
@@ -69,33 +107,164 @@ func (screenState *ScreenState) render() {
 	screenState.Screen.Show()
 }
 
-func getHeader(node *trie.Node) []ScreenRow {
+func getHeader(screenState *ScreenState) []ScreenRow {
+	message := "redis-spectacles ~ Use the arrow keys to navigate."
+	if screenState.ShowMemory {
+		message += " Press 's' to sort by key count or memory."
+	}
+
 	header := []ScreenRow{
 		{
-			Message: "redis-spectacles ~ Use the arrow keys to navigate.",
+			Message: message,
 			Style:   highlighedStyle,
 		},
-		{
-			Message: strings.Repeat("-", 500),
-			Style:   normalStyle,
-		},
 	}
+
+	if screenState.Scanning {
+		width, _ := screenState.Screen.Size()
+		progress := screenState.Progress.Describe()
+		if throttleState := redisscanner.DescribeThrottling(screenState.Shards); throttleState != "" {
+			progress += " [throttle: " + throttleState + "]"
+		}
+		header = append(header, ScreenRow{
+			Message: progress + ". Press 'q' to stop scanning.",
+			Style:   warningStyle,
+		}, ScreenRow{
+			Message: screenState.Progress.Bar(width),
+			Style:   warningStyle,
+		})
+	}
+
+	if screenState.Interruption != "" {
+		header = append(header, ScreenRow{
+			Message: fmt.Sprintf("Partial result, %s before the scan completed.", screenState.Interruption),
+			Style:   errorStyle,
+		})
+	}
+
+	if screenState.Estimator != nil {
+		header = append(header, ScreenRow{
+			Message: screenState.Estimator.DescribeSample() + ".",
+			Style:   warningStyle,
+		})
+	}
+
+	header = append(header, ScreenRow{
+		Message: strings.Repeat("-", 500),
+		Style:   normalStyle,
+	})
 	return header
 }
 
-func getFooter(node *trie.Node) []ScreenRow {
+// getSelectedRowDetails returns rows describing attributes of the keys under the selected prefix.
+func getSelectedRowDetails(screenState *ScreenState) []ScreenRow {
+	details := make([]ScreenRow, 0)
+	if len(screenState.Body) == 0 {
+		return details
+	}
+
+	node := screenState.Body[screenState.CurrentBodyRow].Metadata.(*trie.Node)
+	stats := keystats.OfNode(node)
+	if screenState.Estimator != nil {
+		details = append(details, ScreenRow{
+			Message:     "Estimated keys : " + screenState.Estimator.Describe(node.Count()),
+			Style:       normalStyle,
+			PaddingLeft: 1,
+		})
+	}
+	if screenState.ShowTypes {
+		details = append(details, ScreenRow{
+			Message:     "Types : " + stats.DescribeTypes(),
+			Style:       normalStyle,
+			PaddingLeft: 1,
+		})
+	}
+	if screenState.ShowTTL {
+		details = append(details, ScreenRow{
+			Message:     "TTLs : " + stats.DescribeTTLs(),
+			Style:       normalStyle,
+			PaddingLeft: 1,
+		})
+	}
+	if screenState.ShowObject {
+		details = append(details, ScreenRow{
+			Message:     "Encodings : " + stats.DescribeEncodings(),
+			Style:       normalStyle,
+			PaddingLeft: 1,
+		}, ScreenRow{
+			Message:     "Access : " + stats.DescribeAccess(),
+			Style:       normalStyle,
+			PaddingLeft: 1,
+		})
+	}
+	if screenState.RuleSet != nil {
+		details = append(details, ScreenRow{
+			Message:     "Rules : " + screenState.RuleSet.DescribeMatches(stats),
+			Style:       normalStyle,
+			PaddingLeft: 1,
+		})
+	}
+	return details
+}
+
+func getFooter(screenState *ScreenState, node *trie.Node) []ScreenRow {
+	estimator := screenState.Estimator
 	footer := []ScreenRow{
 		{
-			Message:     fmt.Sprintf("Total key count : %d", node.Count()),
+			Message:     fmt.Sprintf("Total key count : %s", estimator.Describe(node.Count())),
 			Style:       highlighedStyle,
 			PaddingLeft: 1,
 		},
 	}
+
+	if screenState.ShowMemory {
+		bytes := keystats.OfNode(node).EstimatedBytes(estimator.EstimatedCount(node.Count()))
+		footer[0].Message += fmt.Sprintf(", Total memory : %s", utils.FormatBytes(bytes))
+	}
+
+	for _, shard := range screenState.Shards {
+		footer = append(footer, ScreenRow{
+			Message:     describeShardProgress(shard, screenState.Scanning),
+			Style:       highlighedStyle,
+			PaddingLeft: 1,
+		})
+	}
+
+	for _, errorMessage := range screenState.ErrorMessages {
+		footer = append(footer, ScreenRow{
+			Message:     errorMessage,
+			Style:       errorStyle,
+			PaddingLeft: 1,
+		})
+	}
 	return footer
 }
 
-func sortEdges(ScreenState *ScreenState, node *trie.Node, edges []*trie.Edge) []*trie.Edge {
+// describeShardProgress returns which node is scanned for the shard along with number of keys scanned from it,
+// e.g. "Scanning replica 10.0.0.2:6379 of primary 10.0.0.1:6379, 1200 keys scanned of 5000".
+func describeShardProgress(shard *redisscanner.Shard, scanning bool) string {
+	if !scanning {
+		return fmt.Sprintf("Scanned %s, %d keys", shard.Describe(), shard.KeysScanned())
+	}
+
+	message := fmt.Sprintf("Scanning %s, %d keys scanned", shard.Describe(), shard.KeysScanned())
+	if dbSize := shard.DBSize(); dbSize >= 0 {
+		message += fmt.Sprintf(" of %d", dbSize)
+	}
+	return message
+}
+
+func getEdgeBytes(screenState *ScreenState, edge *trie.Edge) int64 {
+	keyCount := screenState.Estimator.EstimatedCount(edge.PrefixCount)
+	return keystats.FromValue(edge.PrefixValue).EstimatedBytes(keyCount)
+}
+
+func sortEdges(screenState *ScreenState, node *trie.Node, edges []*trie.Edge) []*trie.Edge {
 	sort.Slice(edges, func(i int, j int) bool {
+		if screenState.SortByMemory {
+			return getEdgeBytes(screenState, edges[i]) > getEdgeBytes(screenState, edges[j])
+		}
+
 		a := node.Edges[edges[i]]
 		b := node.Edges[edges[j]]
 		return a.Count() > b.Count()
@@ -112,10 +281,18 @@ func formatCount(count int) string {
 	return fmt.Sprintf("%dK", count)
 }
 
-func getStackPrefix(screenState *ScreenState) string {
+func padLeft(message string, width int) string {
+	padding := strings.Repeat(" ", utils.Max(width-len(message), 0))
+	return padding + message
+}
+
+// getStackPrefixes returns key prefix of every node in the stack, starting with the empty prefix of the root.
+func getStackPrefixes(screenState *ScreenState) []string {
 	nodeStack := screenState.NodeStack
 	prefix := ""
+	prefixes := make([]string, 0, nodeStack.Len())
 	for currentNodeElement := nodeStack.Front(); currentNodeElement != nil; currentNodeElement = currentNodeElement.Next() {
+		prefixes = append(prefixes, prefix)
 		nextNodeElement := currentNodeElement.Next()
 		if nextNodeElement == nil {
 			continue
@@ -130,10 +307,40 @@ func getStackPrefix(screenState *ScreenState) string {
 			}
 		}
 	}
-	return prefix
+	return prefixes
+}
+
+func getStackPrefix(screenState *ScreenState) string {
+	prefixes := getStackPrefixes(screenState)
+	return prefixes[len(prefixes)-1]
+}
+
+// findNode returns node of the condensed trie reached by following edges which spell the prefix, nil if there's none.
+// Out of the edges which are prefixes of the remaining prefix, the longest one is followed, e.g. "a:" instead of "a"
+// for "a:b", since the shorter one leads to a different branch.
+func findNode(node *trie.Node, prefix string) *trie.Node {
+	for prefix != "" {
+		var nextNode *trie.Node
+		matchLength := 0
+		for edge, childNode := range node.Edges {
+			edgePrefix := edge.Prefix.(string)
+			if len(edgePrefix) > matchLength && strings.HasPrefix(prefix, edgePrefix) {
+				nextNode = childNode
+				matchLength = len(edgePrefix)
+			}
+		}
+
+		if nextNode == nil {
+			return nil
+		}
+		node = nextNode
+		prefix = prefix[matchLength:]
+	}
+	return node
 }
 
-func updateTrieNodeInScreenState(screenState *ScreenState, node *trie.Node) {
+// updateTrieNodeInScreenState shows children of the node, selecting the row of given prefix if it's present.
+func updateTrieNodeInScreenState(screenState *ScreenState, node *trie.Node, selectedPrefix string) {
 	body := make([]ScreenRow, 0)
 	edges := node.GetEdges()
 	edges = sortEdges(screenState, node, edges)
@@ -142,20 +349,80 @@ func updateTrieNodeInScreenState(screenState *ScreenState, node *trie.Node) {
 	for _, edge := range edges {
 		childNode := node.Edges[edge]
 		count := childNode.Count()
-		countString := formatCount(count)
+		countString := formatCount(screenState.Estimator.EstimatedCount(count))
+		if screenState.Estimator != nil {
+			countString = "~" + countString
+		}
 
-		paddingForRightAlignment := consts.PaddingForRightAlignment - len(countString)
-		padding := strings.Repeat(" ", paddingForRightAlignment)
+		message := padLeft(countString, consts.PaddingForRightAlignment)
+		if screenState.ShowMemory {
+			message += " " + padLeft(utils.FormatBytes(getEdgeBytes(screenState, edge)), consts.PaddingForRightAlignment)
+		}
 
 		prefix := stackPrefix + edge.Prefix.(string)
+		message += " - " + screenState.FormatKey(prefix)
 
-		message := padding + countString + " - " + prefix
-		row := ScreenRow{Message: message, Style: normalStyle, PaddingLeft: 5, Metadata: childNode}
+		// Keys which never expire keep using memory until deleted explicitly, so such prefixes are flagged.
+		style := normalStyle
+		if screenState.ShowTTL && keystats.FromValue(edge.PrefixValue).MostlyNoTTL() {
+			message += " [mostly no expiry]"
+			style = warningStyle
+		}
+		if screenState.RuleSet != nil {
+			if names := screenState.RuleSet.MatchedNames(keystats.FromValue(edge.PrefixValue)); names != "" {
+				message += " [rule: " + names + "]"
+			}
+		}
+		row := ScreenRow{Message: message, Style: style, PaddingLeft: 5, Metadata: childNode, Prefix: prefix}
 		body = append(body, row)
 	}
 
 	screenState.Body = body
 	screenState.CurrentBodyRow = 0
+	for index, row := range body {
+		if row.Prefix == selectedPrefix {
+			screenState.CurrentBodyRow = index
+		}
+	}
+	screenState.render()
+}
+
+// getSelectedPrefix returns prefix of the selected row, empty if there's no row.
+func getSelectedPrefix(screenState *ScreenState) string {
+	if len(screenState.Body) == 0 {
+		return ""
+	}
+	return screenState.Body[screenState.CurrentBodyRow].Prefix
+}
+
+// applySnapshot replaces the trie being shown with its newer snapshot. Nodes in the stack are looked up
+// in the new trie by their prefixes, so that the same prefix and row stay selected.
+func applySnapshot(screenState *ScreenState, root *trie.Node) {
+	prefixes := getStackPrefixes(screenState)
+	selectedPrefix := getSelectedPrefix(screenState)
+
+	nodeStack := list.New()
+	nodeStack.PushBack(root)
+	for _, prefix := range prefixes[1:] {
+		node := findNode(root, prefix)
+		if node == nil {
+			break
+		}
+		nodeStack.PushBack(node)
+	}
+
+	screenState.NodeStack = nodeStack
+	screenState.Estimator = redisscanner.NewEstimator(screenState.Shards, screenState.ScanOptions)
+	screenState.Header = getHeader(screenState)
+	screenState.Footer = getFooter(screenState, root)
+	updateTrieNodeInScreenState(screenState, nodeStack.Back().Value.(*trie.Node), selectedPrefix)
+}
+
+// applyProgress refreshes progress of the scan shown in the header, along with progress of every shard in the footer.
+func applyProgress(screenState *ScreenState, progress redisscanner.Progress) {
+	screenState.Progress = progress
+	screenState.Header = getHeader(screenState)
+	screenState.Footer = getFooter(screenState, screenState.NodeStack.Front().Value.(*trie.Node))
 	screenState.render()
 }
 
@@ -165,26 +432,59 @@ func popNodeFromStack(screenState *ScreenState) {
 		return
 	}
 
+	poppedPrefix := getStackPrefix(screenState)
 	topNodeElement := nodeStack.Back()
 	nodeStack.Remove(topNodeElement)
 	topNodeElement = nodeStack.Back()
 
 	node := topNodeElement.Value.(*trie.Node)
-	updateTrieNodeInScreenState(screenState, node)
+	updateTrieNodeInScreenState(screenState, node, poppedPrefix)
+}
+
+func toggleSortOrder(screenState *ScreenState) {
+	if !screenState.ShowMemory {
+		return
+	}
+
+	screenState.SortByMemory = !screenState.SortByMemory
+	node := screenState.NodeStack.Back().Value.(*trie.Node)
+	updateTrieNodeInScreenState(screenState, node, getSelectedPrefix(screenState))
 }
 
 func pushNodeIntoStack(screenState *ScreenState, node *trie.Node) {
 	nodeStack := screenState.NodeStack
 	nodeStack.PushBack(node)
 
-	updateTrieNodeInScreenState(screenState, node)
+	updateTrieNodeInScreenState(screenState, node, "")
 }
 
-func initScreenState(screen tcell.Screen, node *trie.Node) *ScreenState {
-	header := getHeader(node)
-	footer := getFooter(node)
-	screenState := ScreenState{Screen: screen, Header: header, Footer: footer, NodeStack: list.New()}
-	pushNodeIntoStack(&screenState, node)
+// initScreenState shows the empty trie, which is replaced by snapshots as the keys are scanned.
+func initScreenState(
+	screen tcell.Screen,
+	shards []*redisscanner.Shard,
+	scanOptions redisscanner.ScanOptions,
+	ruleSet *rules.RuleSet,
+	formatKey utils.KeyFormatter,
+	cancelScan context.CancelFunc,
+) *ScreenState {
+	screenState := ScreenState{
+		Screen:      screen,
+		NodeStack:   list.New(),
+		ShowMemory:  scanOptions.MemoryUsage,
+		ShowTypes:   scanOptions.KeyTypes,
+		ShowTTL:     scanOptions.TTL,
+		ShowObject:  scanOptions.ObjectStats,
+		Shards:      shards,
+		ScanOptions: scanOptions,
+		RuleSet:     ruleSet,
+		FormatKey:   formatKey,
+		Scanning:    true,
+		CancelScan:  cancelScan,
+	}
+
+	root := trie.NewNode()
+	screenState.NodeStack.PushBack(root)
+	applySnapshot(&screenState, root)
 	return &screenState
 }
 
@@ -197,44 +497,41 @@ func startEventLoop(screenState *ScreenState) {
 			handleKeyEvent(screenState, event)
 		case *tcell.EventResize:
 			handleResize(screenState, event)
+		case *progressEvent:
+			applyProgress(screenState, event.progress)
+		case *snapshotEvent:
+			if event.completed {
+				screenState.Scanning = false
+				screenState.Interruption = event.interruption
+				screenState.ErrorMessages = event.errorMessages
+			}
+			applySnapshot(screenState, event.node)
 		}
 	}
 }
 
 func handleKeyDown(screenState *ScreenState) {
-	screen := screenState.Screen
 	currentBodyRow := screenState.CurrentBodyRow
 	newBodyRow := currentBodyRow + 1
 
 	maxBodySize := len(screenState.Body)
-	if newBodyRow == maxBodySize {
+	if newBodyRow >= maxBodySize {
 		return
 	}
 
-	currentRow := currentBodyRow + len(screenState.Header)
-	newRow := newBodyRow + len(screenState.Header)
-
-	setRowBackground(screen, currentRow, normalStyle)
-	setRowBackground(screen, newRow, highlighedStyle)
-	screen.Show()
 	screenState.CurrentBodyRow++
+	screenState.render()
 }
 
 func handleKeyUp(screenState *ScreenState) {
-	screen := screenState.Screen
 	currentBodyRow := screenState.CurrentBodyRow
 	newBodyRow := currentBodyRow - 1
 	if newBodyRow < 0 {
 		return
 	}
 
-	currentRow := currentBodyRow + len(screenState.Header)
-	newRow := newBodyRow + len(screenState.Header)
-
-	setRowBackground(screen, currentRow, normalStyle)
-	setRowBackground(screen, newRow, highlighedStyle)
-	screen.Show()
 	screenState.CurrentBodyRow--
+	screenState.render()
 }
 
 func handleKeyLeft(screenState *ScreenState) {
@@ -242,6 +539,10 @@ func handleKeyLeft(screenState *ScreenState) {
 }
 
 func handleKeyRight(screenState *ScreenState) {
+	if len(screenState.Body) == 0 {
+		return
+	}
+
 	screenRow := screenState.Body[screenState.CurrentBodyRow]
 	node := screenRow.Metadata.(*trie.Node)
 	if len(node.Edges) == 0 {
@@ -254,11 +555,22 @@ func handleKeyRight(screenState *ScreenState) {
 func handleKeyEvent(screenState *ScreenState, event *tcell.EventKey) {
 	switch event.Key() {
 	case tcell.KeyRune:
-		if event.Rune() == 'q' {
+		switch event.Rune() {
+		case 'q':
+			if screenState.Scanning {
+				screenState.CancelScan()
+				return
+			}
 			screenState.Screen.Fini()
 			os.Exit(0)
+		case 's':
+			toggleSortOrder(screenState)
 		}
 	case tcell.KeyCtrlC:
+		if screenState.Scanning {
+			screenState.CancelScan()
+			return
+		}
 		screenState.Screen.Fini()
 		os.Exit(1)
 	case tcell.KeyUp:
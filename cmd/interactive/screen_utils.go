@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/Ashish-Bansal/redis-spectacles/internal/utils"
 	"github.com/gdamore/tcell"
 )
 
@@ -26,11 +25,13 @@ func initScreen() tcell.Screen {
 	return screen
 }
 
+// setStringInScreen shows the message character by character, cutting it at the edge of the screen.
 func setStringInScreen(screen tcell.Screen, columnStart int, row int, message string, style tcell.Style) {
 	width, _ := screen.Size()
-	length := utils.Min(len(message), width)
-	for index := 0; index < length; index++ {
-		character := rune(message[index])
+	for _, character := range message {
+		if columnStart >= width {
+			break
+		}
 		screen.SetContent(columnStart, row, character, []rune(""), style)
 		columnStart++
 	}
@@ -8,17 +8,10 @@ import (
 
 	"github.com/Ashish-Bansal/redis-spectacles/cmd/interactive"
 	"github.com/Ashish-Bansal/redis-spectacles/cmd/noninteractive"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/flags"
 )
 
-const redisURLArgName string = "url"
-
 func main() {
-	redisURLFlag := &cli.StringFlag{
-		Name:     redisURLArgName,
-		Usage:    "Redis URL to scan",
-		Required: true,
-	}
-
 	app := &cli.App{
 		Commands: []*cli.Command{
 			{
@@ -28,9 +21,34 @@ func main() {
 					noninteractive.ExecuteNonInteractive(c)
 					return nil
 				},
-				Flags: []cli.Flag{
-					redisURLFlag,
+				Flags: flags.PrintFlags(),
+			},
+			{
+				Name:  "bigkeys",
+				Usage: "Print largest keys grouped by their prefixes",
+				Action: func(c *cli.Context) error {
+					noninteractive.ExecuteBigKeys(c)
+					return nil
+				},
+				Flags: flags.BigKeysFlags(),
+			},
+			{
+				Name:  "check",
+				Usage: "Validate keyspace against declared schema and print JSON report of the violations",
+				Action: func(c *cli.Context) error {
+					noninteractive.ExecuteCheck(c)
+					return nil
+				},
+				Flags: flags.CheckFlags(),
+			},
+			{
+				Name:  "lint",
+				Usage: "Report inconsistent delimiters, case conflicts, overlong and binary keys",
+				Action: func(c *cli.Context) error {
+					noninteractive.ExecuteLint(c)
+					return nil
 				},
+				Flags: flags.LintFlags(),
 			},
 			{
 				Name:  "interactive",
@@ -39,9 +57,7 @@ func main() {
 					interactive.ExecuteInteractive(c)
 					return nil
 				},
-				Flags: []cli.Flag{
-					redisURLFlag,
-				},
+				Flags: flags.ScanFlags(),
 			},
 		},
 	}
@@ -0,0 +1,79 @@
+package noninteractive
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/Ashish-Bansal/redis-spectacles/internal/consts"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/flags"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/keystats"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/redisscanner"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/trie"
+	"github.com/urfave/cli/v2"
+)
+
+// bigKeyGroup is a prefix of the condensed trie along with the largest keys under it.
+type bigKeyGroup struct {
+	prefix  string
+	count   int
+	bigKeys []keystats.BigKey
+}
+
+// ExecuteBigKeys scans the keyspace and prints the largest keys grouped by their prefixes.
+func ExecuteBigKeys(c *cli.Context) {
+	scanOptions := flags.GetScanOptions(c)
+	scanOptions.BigKeys = true
+	scanOptions.TopKeys = c.Int(consts.TopKeysArgName)
+	formatKey, err := flags.GetKeyFormatter(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	node, shards, interruption := scanKeyspace(c, scanOptions)
+	printScanSummary(node, shards, interruption)
+	estimator := redisscanner.NewEstimator(shards, scanOptions)
+	if estimator != nil {
+		fmt.Println(estimator.DescribeSample())
+	}
+
+	groups := make([]bigKeyGroup, 0)
+	collectBigKeyGroups(node, "", c.Int(consts.GroupDepthArgName), &groups)
+	sort.SliceStable(groups, func(i int, j int) bool {
+		return groups[i].bigKeys[0].Size > groups[j].bigKeys[0].Size
+	})
+
+	for _, group := range groups {
+		fmt.Printf("%s (%s keys)\n", formatKey(group.prefix), estimator.Describe(group.count))
+		for _, bigKey := range group.bigKeys {
+			fmt.Printf("\t%s\t%s\t%s\n", bigKey.DescribeSize(), bigKey.Type, formatKey(bigKey.Key))
+		}
+	}
+
+	exitOnScanErrors(shards, scanOptions)
+}
+
+// collectBigKeyGroups groups the keys by prefixes which are given number of condensed trie levels deep.
+// Keys ending before that depth form a group of their own, groups without sized keys are skipped.
+func collectBigKeyGroups(node *trie.Node, prefix string, depth int, groups *[]bigKeyGroup) {
+	for _, edge := range node.GetEdges() {
+		childNode := node.Edges[edge]
+		childPrefix := prefix + edge.Prefix.(string)
+		if depth > 1 && len(childNode.Edges) != 0 {
+			if childNode.DataCount != 0 {
+				appendBigKeyGroup(groups, childPrefix, childNode.DataCount, childNode.DataValue)
+			}
+			collectBigKeyGroups(childNode, childPrefix, depth-1, groups)
+			continue
+		}
+		appendBigKeyGroup(groups, childPrefix, edge.PrefixCount, edge.PrefixValue)
+	}
+}
+
+func appendBigKeyGroup(groups *[]bigKeyGroup, prefix string, count int, value interface{}) {
+	bigKeys := keystats.FromValue(value).BigKeys
+	if len(bigKeys) == 0 {
+		return
+	}
+	*groups = append(*groups, bigKeyGroup{prefix: prefix, count: count, bigKeys: bigKeys})
+}
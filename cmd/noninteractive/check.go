@@ -0,0 +1,207 @@
+package noninteractive
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Ashish-Bansal/redis-spectacles/internal/flags"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/keystats"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/redisscanner"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/rules"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/trie"
+	"github.com/urfave/cli/v2"
+)
+
+// Exit codes of the check command, failures to connect or scan exit with exitCheckFailed too.
+const (
+	exitCheckFailed    = 1
+	exitCheckViolation = 2
+)
+
+// Kinds of the schema violations.
+const (
+	unclassifiedViolation = "unclassified"
+	maxKeysViolation      = "max_keys"
+	ttlViolation          = "ttl"
+	typeViolation         = "type"
+)
+
+// maxSampleKeys is number of keys listed for every violation.
+const maxSampleKeys = 5
+
+// checkReport is the machine readable result of the check command, printed as JSON.
+type checkReport struct {
+	Passed       bool             `json:"passed"`
+	Complete     bool             `json:"complete"`
+	Interruption string           `json:"interruption,omitempty"`
+	Errors       []string         `json:"errors"`
+	KeysScanned  int              `json:"keys_scanned"`
+	Estimated    bool             `json:"estimated"`
+	Rules        []ruleReport     `json:"rules"`
+	Violations   []*ruleViolation `json:"violations"`
+}
+
+type ruleReport struct {
+	Name  string `json:"name"`
+	Owner string `json:"owner,omitempty"`
+	Keys  int64  `json:"keys"`
+}
+
+type ruleViolation struct {
+	// Rule is name of the violated rule, empty for keys not matching any rule.
+	Rule       string   `json:"rule,omitempty"`
+	Kind       string   `json:"kind"`
+	Keys       int64    `json:"keys"`
+	Limit      int64    `json:"limit,omitempty"`
+	Message    string   `json:"message"`
+	SampleKeys []string `json:"sample_keys"`
+}
+
+// ExecuteCheck scans the keyspace, validates it against the schema and prints JSON report of the violations.
+// It exits with exitCheckViolation in case of violations and exitCheckFailed if the scan was incomplete.
+func ExecuteCheck(c *cli.Context) {
+	scanOptions := flags.GetScanOptions(c)
+	schema, err := flags.GetSchema(c, &scanOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	formatKey, err := flags.GetKeyFormatter(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	node, shards, interruption := scanKeyspace(c, scanOptions)
+	estimator := redisscanner.NewEstimator(shards, scanOptions)
+	report := checkReport{
+		Complete:     interruption == "",
+		Interruption: interruption,
+		Errors:       redisscanner.DescribeScanErrors(shards, scanOptions),
+		KeysScanned:  node.Count(),
+		Estimated:    estimator != nil,
+		Rules:        make([]ruleReport, 0),
+		Violations:   make([]*ruleViolation, 0),
+	}
+
+	summaries := schema.Summarize(keystats.OfNode(node), node.Count())
+	violations := make(map[violationKey]*ruleViolation)
+	for index, summary := range summaries {
+		keys := int64(estimator.EstimatedCount(int(summary.Keys)))
+		if summary.Rule == nil {
+			if keys != 0 {
+				violations[violationKey{index, unclassifiedViolation}] = &ruleViolation{
+					Kind:    unclassifiedViolation,
+					Keys:    keys,
+					Message: fmt.Sprintf("%d keys don't match any pattern of the schema", keys),
+				}
+			}
+			continue
+		}
+
+		rule := summary.Rule
+		report.Rules = append(report.Rules, ruleReport{Name: rule.Name, Owner: rule.Owner, Keys: keys})
+		if rule.MaxKeys > 0 && keys > rule.MaxKeys {
+			violations[violationKey{index, maxKeysViolation}] = &ruleViolation{
+				Kind:    maxKeysViolation,
+				Keys:    keys,
+				Limit:   rule.MaxKeys,
+				Message: fmt.Sprintf("%d keys exceed the limit of %d keys", keys, rule.MaxKeys),
+			}
+		}
+		if summary.TTLViolations != 0 {
+			ttlKeys := int64(estimator.EstimatedCount(int(summary.TTLViolations)))
+			message := fmt.Sprintf("%d keys without expiry, TTL is required", ttlKeys)
+			if rule.TTL == rules.TTLPolicyNone {
+				message = fmt.Sprintf("%d keys expire, TTL isn't allowed", ttlKeys)
+			}
+			violations[violationKey{index, ttlViolation}] = &ruleViolation{
+				Kind:    ttlViolation,
+				Keys:    ttlKeys,
+				Message: message,
+			}
+		}
+		if summary.TypeViolations != 0 {
+			typeKeys := int64(estimator.EstimatedCount(int(summary.TypeViolations)))
+			violations[violationKey{index, typeViolation}] = &ruleViolation{
+				Kind:    typeViolation,
+				Keys:    typeKeys,
+				Message: fmt.Sprintf("%d keys aren't of allowed types %v", typeKeys, rule.Types),
+			}
+		}
+	}
+
+	collectSampleKeys(node, "", len(schema.Rules), violations)
+	for index, summary := range summaries {
+		for _, kind := range []string{unclassifiedViolation, maxKeysViolation, ttlViolation, typeViolation} {
+			violation, ok := violations[violationKey{index, kind}]
+			if !ok {
+				continue
+			}
+			if summary.Rule != nil {
+				violation.Rule = summary.Rule.Name
+			}
+			if violation.SampleKeys == nil {
+				violation.SampleKeys = make([]string, 0)
+			}
+			for keyIndex, key := range violation.SampleKeys {
+				violation.SampleKeys[keyIndex] = formatKey(key)
+			}
+			report.Violations = append(report.Violations, violation)
+		}
+	}
+
+	report.Passed = report.Complete && len(report.Errors) == 0 && len(report.Violations) == 0
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(report.Violations) != 0 {
+		os.Exit(exitCheckViolation)
+	}
+	if !report.Passed {
+		os.Exit(exitCheckFailed)
+	}
+}
+
+// violationKey identifies violation by index of the rule in the summaries and kind of the violation.
+type violationKey struct {
+	summaryIndex int
+	kind         string
+}
+
+// collectSampleKeys walks keys of the trie and adds some of the keys causing each violation to it.
+// Keys of the max_keys violations are sampled regardless of their other attributes.
+func collectSampleKeys(node *trie.Node, prefix string, unclassifiedIndex int, violations map[violationKey]*ruleViolation) {
+	if node.DataCount != 0 {
+		stats := keystats.FromValue(node.DataValue)
+		classifiedKeys := int64(0)
+		for index, ruleStats := range stats.Rules {
+			classifiedKeys += ruleStats.Keys
+			if ruleStats.Keys != 0 {
+				addSampleKey(violations[violationKey{index, maxKeysViolation}], prefix)
+			}
+			if ruleStats.TTLViolations != 0 {
+				addSampleKey(violations[violationKey{index, ttlViolation}], prefix)
+			}
+			if ruleStats.TypeViolations != 0 {
+				addSampleKey(violations[violationKey{index, typeViolation}], prefix)
+			}
+		}
+		if int64(node.DataCount) > classifiedKeys {
+			addSampleKey(violations[violationKey{unclassifiedIndex, unclassifiedViolation}], prefix)
+		}
+	}
+
+	for _, edge := range node.GetEdges() {
+		collectSampleKeys(node.Edges[edge], prefix+edge.Prefix.(string), unclassifiedIndex, violations)
+	}
+}
+
+func addSampleKey(violation *ruleViolation, key string) {
+	if violation != nil && len(violation.SampleKeys) < maxSampleKeys {
+		violation.SampleKeys = append(violation.SampleKeys, key)
+	}
+}
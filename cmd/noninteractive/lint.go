@@ -0,0 +1,47 @@
+package noninteractive
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Ashish-Bansal/redis-spectacles/internal/consts"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/flags"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/lint"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/redisscanner"
+	"github.com/urfave/cli/v2"
+)
+
+// ExecuteLint scans the keyspace and prints naming problems of the keys, each along with sample keys.
+func ExecuteLint(c *cli.Context) {
+	scanOptions := flags.GetScanOptions(c)
+	formatKey, err := flags.GetKeyFormatter(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	node, shards, interruption := scanKeyspace(c, scanOptions)
+	printScanSummary(node, shards, interruption)
+	estimator := redisscanner.NewEstimator(shards, scanOptions)
+	if estimator != nil {
+		fmt.Println(estimator.DescribeSample())
+	}
+
+	branches := make([]string, 0)
+	for _, shard := range shards {
+		if shard.Branch != "" {
+			branches = append(branches, shard.Branch)
+		}
+	}
+	findings := lint.Lint(node, lint.Options{
+		Delimiters:   c.String(consts.DelimiterArgName),
+		MaxKeyLength: c.Int(consts.MaxKeyLengthArgName),
+		Branches:     branches,
+	})
+	for _, finding := range findings {
+		fmt.Printf("[%s] %s (%s keys)\n", finding.Kind, finding.Message, estimator.Describe(finding.Count))
+		for _, key := range finding.SampleKeys {
+			fmt.Printf("\t%s\n", formatKey(key))
+		}
+	}
+
+	exitOnScanErrors(shards, scanOptions)
+}
@@ -3,35 +3,136 @@ package noninteractive
 import (
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 
 	"github.com/Ashish-Bansal/redis-spectacles/internal/consts"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/flags"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/keystats"
 	"github.com/Ashish-Bansal/redis-spectacles/internal/redisscanner"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/rules"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/utils"
 	"github.com/Ashish-Bansal/redis-spectacles/pkg/trie"
 	"github.com/urfave/cli/v2"
 )
 
-func ExecuteNonInteractive(c *cli.Context) {
-	redisURL := c.String(consts.RedisURLArgName)
-	scanBatchSize := c.Int64(consts.ScanBatchSizeArgName)
-	scanPattern := c.String(consts.ScanPattern)
+const noTTLReport = "no-ttl"
 
-	client, err := redisscanner.GetRedisClient(redisURL)
+func ExecuteNonInteractive(c *cli.Context) {
+	scanOptions := flags.GetScanOptions(c)
+	report := c.String(consts.ReportArgName)
+	switch report {
+	case "":
+	case noTTLReport:
+		scanOptions.TTL = true
+	default:
+		log.Fatalf("Unknown report %q", report)
+	}
+	ruleSet, err := flags.GetRuleSet(c, &scanOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	formatKey, err := flags.GetKeyFormatter(c)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	keyReceiver := make(chan string, 100)
-	go redisscanner.ScanRedisKeys(client, scanPattern, scanBatchSize, keyReceiver)
+	node, shards, interruption := scanKeyspace(c, scanOptions)
+	printScanSummary(node, shards, interruption)
+	estimator := redisscanner.NewEstimator(shards, scanOptions)
+	if estimator != nil {
+		fmt.Println(estimator.DescribeSample())
+	}
+
+	if report == noTTLReport {
+		printNoTTLReport(node, estimator, formatKey)
+	} else if ruleSet != nil {
+		printRuleRollup(node, ruleSet, estimator)
+	} else if estimator != nil || scanOptions.MemoryUsage || scanOptions.KeyTypes || scanOptions.TTL || scanOptions.ObjectStats {
+		printPrefixesWithStats(node, scanOptions, estimator, formatKey)
+	} else {
+		prefixes := make([]string, 0)
+		node.DFS(func(item interface{}, count int) {
+			prefixes = append(prefixes, formatKey(item.(string)))
+		})
+		fmt.Println(prefixes)
+	}
+
+	exitOnScanErrors(shards, scanOptions)
+}
 
-	node := trie.NewNode()
-	for key := range keyReceiver {
-		node.Insert(key)
+// printPrefixesWithStats prints every prefix on its own line along with its key count
+// and the collected stats, as tab separated columns. Counts of the sampled keys are printed as estimates.
+func printPrefixesWithStats(
+	node *trie.Node,
+	scanOptions redisscanner.ScanOptions,
+	estimator *redisscanner.Estimator,
+	formatKey utils.KeyFormatter,
+) {
+	node.DFSValues(func(item interface{}, count int, value interface{}) {
+		stats := keystats.FromValue(value)
+		columns := []string{formatKey(item.(string)), estimator.Describe(count)}
+		if scanOptions.MemoryUsage {
+			bytes := stats.EstimatedBytes(estimator.EstimatedCount(count))
+			columns = append(columns, utils.FormatBytes(bytes))
+		}
+		if scanOptions.KeyTypes {
+			columns = append(columns, stats.DescribeTypes())
+		}
+		if scanOptions.TTL {
+			columns = append(columns, stats.DescribeTTLs())
+		}
+		if scanOptions.ObjectStats {
+			columns = append(columns, stats.DescribeEncodings(), stats.DescribeAccess())
+		}
+		fmt.Println(strings.Join(columns, "\t"))
+	})
+}
+
+// printNoTTLReport prints prefixes where most of the keys never expire, ones with most such keys first.
+func printNoTTLReport(node *trie.Node, estimator *redisscanner.Estimator, formatKey utils.KeyFormatter) {
+	type noTTLPrefix struct {
+		prefix string
+		count  int
+		stats  keystats.KeyStats
 	}
-	node.Condense()
 
-	prefixes := make([]string, 0)
-	node.DFS(func(item interface{}, count int) {
-		prefixes = append(prefixes, item.(string))
+	prefixes := make([]noTTLPrefix, 0)
+	node.DFSValues(func(item interface{}, count int, value interface{}) {
+		stats := keystats.FromValue(value)
+		if stats.MostlyNoTTL() {
+			prefixes = append(prefixes, noTTLPrefix{item.(string), count, stats})
+		}
+	})
+	sort.SliceStable(prefixes, func(i int, j int) bool {
+		return prefixes[i].stats.NoTTLKeys > prefixes[j].stats.NoTTLKeys
 	})
-	fmt.Println(prefixes)
+
+	for _, prefix := range prefixes {
+		fmt.Printf(
+			"%s\t%s\t%s without expiry (%.0f%%)\n",
+			formatKey(prefix.prefix),
+			estimator.Describe(prefix.count),
+			estimator.Describe(int(prefix.stats.NoTTLKeys)),
+			prefix.stats.NoTTLRatio()*100,
+		)
+	}
+}
+
+// printRuleRollup prints number of keys classified by every rule along with its owner,
+// followed by the unclassified keys. Keys violating TTL policy of the rule are counted too.
+func printRuleRollup(node *trie.Node, ruleSet *rules.RuleSet, estimator *redisscanner.Estimator) {
+	for _, summary := range ruleSet.Summarize(keystats.OfNode(node), node.Count()) {
+		columns := []string{summary.Name(), "", estimator.Describe(int(summary.Keys))}
+		if summary.Rule != nil {
+			columns[1] = summary.Rule.Owner
+			switch summary.Rule.TTL {
+			case rules.TTLPolicyRequired:
+				columns = append(columns, estimator.Describe(int(summary.TTLViolations))+" without expiry")
+			case rules.TTLPolicyNone:
+				columns = append(columns, estimator.Describe(int(summary.TTLViolations))+" expiring")
+			}
+		}
+		fmt.Println(strings.Join(columns, "\t"))
+	}
 }
@@ -0,0 +1,146 @@
+package noninteractive
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Ashish-Bansal/redis-spectacles/internal/consts"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/flags"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/redisscanner"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/utils"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/trie"
+	"github.com/urfave/cli/v2"
+)
+
+const progressInterval = time.Second
+const nonTerminalProgressInterval = 10 * time.Second
+
+// scanKeyspace scans the redis deployment given by command line flags and returns condensed trie of the keys
+// along with the scanned shards and reason of the interruption, empty if the scan completed.
+// Progress is reported on stderr.
+func scanKeyspace(
+	c *cli.Context,
+	scanOptions redisscanner.ScanOptions,
+) (*trie.Node, []*redisscanner.Shard, string) {
+	if err := flags.ValidateScanOptions(c, scanOptions); err != nil {
+		log.Fatal(err)
+	}
+	tokenizer, err := flags.GetTokenizer(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	connectionOptions := flags.GetConnectionOptions(c)
+	shards, err := redisscanner.GetShards(connectionOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, shard := range shards {
+		fmt.Fprintf(os.Stderr, "Scanning %s\n", shard.Describe())
+	}
+
+	node, checkpointer, err := redisscanner.PrepareCheckpointing(
+		shards,
+		c.String(consts.CheckpointFileArgName),
+		c.Duration(consts.CheckpointIntervalArgName),
+		c.Bool(consts.ResumeArgName),
+		flags.GetCheckpointSettings(c, scanOptions),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	redisscanner.FetchDBSizes(shards)
+	tracker := redisscanner.NewProgressTracker(shards, scanOptions)
+	stopReportingProgress := reportProgress(tracker)
+
+	ctx, cancel := utils.NewInterruptibleContext(c.Duration(consts.MaxDurationArgName))
+	keyReceiver := make(chan redisscanner.KeyRecord, 100)
+	redisscanner.ScanShards(ctx, shards, scanOptions, keyReceiver)
+
+	for record := range keyReceiver {
+		node.InsertWithValue(record.Tokenize(tokenizer), record.Value())
+		checkpointer.Track(record)
+	}
+	stopReportingProgress()
+	interruption := utils.DescribeInterruption(ctx)
+	cancel()
+	checkpointer.Save()
+	if err := checkpointer.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	node.Condense()
+	return node, shards, interruption
+}
+
+// printScanSummary prints whether the result is partial, along with number of keys scanned from every shard.
+func printScanSummary(node *trie.Node, shards []*redisscanner.Shard, interruption string) {
+	if interruption != "" {
+		fmt.Printf("Partial result, %s after scanning %d keys\n", interruption, node.Count())
+	}
+
+	if len(shards) > 1 {
+		for _, shard := range shards {
+			fmt.Printf("%s: %d keys\n", shard.Name, shard.KeysScanned())
+		}
+	}
+}
+
+// reportProgress prints progress of the scan on stderr at fixed rate until returned function is called.
+// On terminal, progress is refreshed in place, otherwise it's printed on a new line less frequently.
+func reportProgress(tracker *redisscanner.ProgressTracker) func() {
+	interval := progressInterval
+	isTerminal := isTerminal(os.Stderr)
+	if !isTerminal {
+		interval = nonTerminalProgressInterval
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				message := tracker.Progress().Describe()
+				if isTerminal {
+					fmt.Fprint(os.Stderr, "\r\033[K"+message)
+				} else {
+					fmt.Fprintln(os.Stderr, message)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		if isTerminal {
+			fmt.Fprint(os.Stderr, "\r\033[K")
+		}
+		fmt.Fprintln(os.Stderr, tracker.Progress().Describe())
+	}
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// exitOnScanErrors reports failed scans of the shards on stderr and exits with non-zero status if there's any.
+func exitOnScanErrors(shards []*redisscanner.Shard, scanOptions redisscanner.ScanOptions) {
+	scanErrors := redisscanner.DescribeScanErrors(shards, scanOptions)
+	for _, scanError := range scanErrors {
+		fmt.Fprintln(os.Stderr, scanError)
+	}
+	if len(scanErrors) != 0 {
+		os.Exit(1)
+	}
+}
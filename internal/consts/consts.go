@@ -1,6 +1,53 @@
 package consts
 
 const RedisURLArgName string = "url"
+const ClusterModeArgName string = "cluster"
+const SentinelAddrsArgName string = "sentinel-addrs"
+const MasterNameArgName string = "master-name"
+const PreferReplicaArgName string = "prefer-replica"
+const AllowPrimaryFallbackArgName string = "allow-primary-fallback"
+const DBArgName string = "db"
+const AllDatabasesArgName string = "all-dbs"
+const UsernameArgName string = "username"
+const PasswordArgName string = "password"
+const TLSArgName string = "tls"
+const TLSCACertArgName string = "tls-ca-cert"
+const TLSCertArgName string = "tls-cert"
+const TLSKeyArgName string = "tls-key"
+const TLSServerNameArgName string = "tls-server-name"
+const TLSSkipVerifyArgName string = "tls-skip-verify"
+const ConnectTimeoutArgName string = "connect-timeout"
+const ReadTimeoutArgName string = "read-timeout"
 const ScanBatchSizeArgName string = "batch-size"
 const ScanPattern string = "scan-pattern"
+const ScanTypeArgName string = "type"
+const SampleFractionArgName string = "sample-fraction"
+const SampleMethodArgName string = "sample-method"
+const ScanRetriesArgName string = "scan-retries"
+const MaxDurationArgName string = "max-duration"
+const MaxOpsArgName string = "max-ops"
+const AdaptiveThrottleArgName string = "adaptive-throttle"
+const LatencyThresholdArgName string = "latency-threshold"
+const ServerOpsThresholdArgName string = "server-ops-threshold"
+const KeyTypesArgName string = "key-types"
+const TTLArgName string = "ttl"
+const ObjectStatsArgName string = "object-stats"
+const MemoryUsageArgName string = "memory-usage"
+const MemorySampleRateArgName string = "memory-sample-rate"
+const MetadataBatchSizeArgName string = "metadata-batch-size"
+const MetadataWorkersArgName string = "metadata-workers"
+const TokenizerArgName string = "tokenizer"
+const DelimiterArgName string = "delimiter"
+const PatternsArgName string = "patterns"
+const DisplayArgName string = "display"
+const RulesArgName string = "rules"
+const CheckpointFileArgName string = "checkpoint-file"
+const CheckpointIntervalArgName string = "checkpoint-interval"
+const ResumeArgName string = "resume"
+const ReportArgName string = "report"
+const TopKeysArgName string = "top-keys"
+const GroupDepthArgName string = "group-depth"
+const SchemaArgName string = "schema"
+const MaxKeyLengthArgName string = "max-key-length"
+const EnvVarPrefix string = "REDIS_SPECTACLES_"
 const PaddingForRightAlignment int = 8
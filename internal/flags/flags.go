@@ -0,0 +1,448 @@
+package flags
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/Ashish-Bansal/redis-spectacles/internal/consts"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/redisscanner"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/rules"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/utils"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/iterator"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/pattern"
+)
+
+// envVars returns environment variable names backing the flag e.g. REDIS_SPECTACLES_BATCH_SIZE for batch-size.
+func envVars(argName string) []string {
+	name := strings.ToUpper(strings.ReplaceAll(argName, "-", "_"))
+	return []string{consts.EnvVarPrefix + name}
+}
+
+func connectionFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    consts.RedisURLArgName,
+			Usage:   "Redis URL to scan. With sentinel, only credentials and DB are used from it",
+			EnvVars: envVars(consts.RedisURLArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.ClusterModeArgName,
+			Usage:   "Treat URL as a cluster seed node and scan every master shard",
+			EnvVars: envVars(consts.ClusterModeArgName),
+		},
+		&cli.StringSliceFlag{
+			Name:    consts.SentinelAddrsArgName,
+			Usage:   "Sentinel addresses (host:port) used to discover redis",
+			EnvVars: envVars(consts.SentinelAddrsArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.MasterNameArgName,
+			Usage:   "Name of the master monitored by sentinels",
+			EnvVars: envVars(consts.MasterNameArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.PreferReplicaArgName,
+			Usage:   "Scan an online replica (found via INFO replication) instead of the primary",
+			EnvVars: envVars(consts.PreferReplicaArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.AllowPrimaryFallbackArgName,
+			Usage:   "Scan the primary when no replica is available in replica preferring mode",
+			EnvVars: envVars(consts.AllowPrimaryFallbackArgName),
+		},
+		&cli.IntFlag{
+			Name:    consts.DBArgName,
+			Usage:   "Database to scan, overrides the one given in URL",
+			EnvVars: envVars(consts.DBArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.AllDatabasesArgName,
+			Usage:   "Scan every non-empty database, each shown as a db<index>/ branch",
+			EnvVars: envVars(consts.AllDatabasesArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.UsernameArgName,
+			Usage:   "ACL username used to authenticate (redis 6+), username given in URL is ignored",
+			EnvVars: envVars(consts.UsernameArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.PasswordArgName,
+			Usage:   "Password used to authenticate, overrides the one given in URL",
+			EnvVars: envVars(consts.PasswordArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.TLSArgName,
+			Usage:   "Connect using TLS, implied by rediss:// URL scheme",
+			EnvVars: envVars(consts.TLSArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.TLSCACertArgName,
+			Usage:   "PEM bundle of CA certificates used to verify the server",
+			EnvVars: envVars(consts.TLSCACertArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.TLSCertArgName,
+			Usage:   "PEM client certificate used for client authentication",
+			EnvVars: envVars(consts.TLSCertArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.TLSKeyArgName,
+			Usage:   "PEM private key of the client certificate",
+			EnvVars: envVars(consts.TLSKeyArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.TLSServerNameArgName,
+			Usage:   "Server name (SNI) used to verify the server certificate",
+			EnvVars: envVars(consts.TLSServerNameArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.TLSSkipVerifyArgName,
+			Usage:   "Skip verification of the server certificate, meant only for development",
+			EnvVars: envVars(consts.TLSSkipVerifyArgName),
+		},
+		&cli.DurationFlag{
+			Name:    consts.ConnectTimeoutArgName,
+			Usage:   "Timeout for establishing new connections",
+			Value:   5 * time.Second,
+			EnvVars: envVars(consts.ConnectTimeoutArgName),
+		},
+		&cli.DurationFlag{
+			Name:    consts.ReadTimeoutArgName,
+			Usage:   "Timeout for reading replies of redis commands",
+			Value:   3 * time.Second,
+			EnvVars: envVars(consts.ReadTimeoutArgName),
+		},
+	}
+}
+
+func scanFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    consts.ScanPattern,
+			Usage:   "Glob-style pattern passed as MATCH argument of SCAN",
+			EnvVars: envVars(consts.ScanPattern),
+		},
+		&cli.Int64Flag{
+			Name:    consts.ScanBatchSizeArgName,
+			Usage:   "Number of keys requested per SCAN call, passed as COUNT argument",
+			Value:   1000,
+			EnvVars: envVars(consts.ScanBatchSizeArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.ScanTypeArgName,
+			Usage:   "Scan only keys of given type e.g. string, hash (filtered locally on servers older than redis 6)",
+			EnvVars: envVars(consts.ScanTypeArgName),
+		},
+		&cli.Float64Flag{
+			Name:    consts.SampleFractionArgName,
+			Usage:   "Sample given fraction (0-1] of the keys and estimate counts of the whole keyspace, 0 scans every key",
+			EnvVars: envVars(consts.SampleFractionArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.SampleMethodArgName,
+			Usage:   "How keys are sampled, scan stops SCAN early while randomkey uses RANDOMKEY",
+			Value:   redisscanner.SampleMethodScan,
+			EnvVars: envVars(consts.SampleMethodArgName),
+		},
+		&cli.IntFlag{
+			Name:    consts.ScanRetriesArgName,
+			Usage:   "Number of times failing SCAN call is retried from the last cursor",
+			Value:   5,
+			EnvVars: envVars(consts.ScanRetriesArgName),
+		},
+		&cli.DurationFlag{
+			Name:    consts.MaxDurationArgName,
+			Usage:   "Stop scanning after given duration and show partial result, 0 means no limit",
+			EnvVars: envVars(consts.MaxDurationArgName),
+		},
+		&cli.Float64Flag{
+			Name:    consts.MaxOpsArgName,
+			Usage:   "Maximum number of SCAN calls per second sent to each node, 0 means no limit",
+			EnvVars: envVars(consts.MaxOpsArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.AdaptiveThrottleArgName,
+			Usage:   "Back off when SCAN latency or server load rises above the thresholds",
+			EnvVars: envVars(consts.AdaptiveThrottleArgName),
+		},
+		&cli.DurationFlag{
+			Name:    consts.LatencyThresholdArgName,
+			Usage:   "SCAN round trip time above which adaptive throttling backs off",
+			Value:   20 * time.Millisecond,
+			EnvVars: envVars(consts.LatencyThresholdArgName),
+		},
+		&cli.Int64Flag{
+			Name:    consts.ServerOpsThresholdArgName,
+			Usage:   "instantaneous_ops_per_sec above which adaptive throttling backs off, 0 disables the check",
+			EnvVars: envVars(consts.ServerOpsThresholdArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.KeyTypesArgName,
+			Usage:   "Look up type of the keys using TYPE and show count of keys per type for each prefix",
+			EnvVars: envVars(consts.KeyTypesArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.TTLArgName,
+			Usage:   "Look up TTL of the keys using PTTL and show TTL distribution for each prefix",
+			EnvVars: envVars(consts.TTLArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.ObjectStatsArgName,
+			Usage:   "Look up encoding and idle time (access frequency under LFU policy) of the keys using OBJECT",
+			EnvVars: envVars(consts.ObjectStatsArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.MemoryUsageArgName,
+			Usage:   "Measure memory usage of the keys using MEMORY USAGE and aggregate it per prefix",
+			EnvVars: envVars(consts.MemoryUsageArgName),
+		},
+		&cli.Float64Flag{
+			Name:    consts.MemorySampleRateArgName,
+			Usage:   "Fraction of the keys whose memory usage is measured, rest is extrapolated",
+			Value:   1,
+			EnvVars: envVars(consts.MemorySampleRateArgName),
+		},
+		&cli.IntFlag{
+			Name:    consts.MetadataBatchSizeArgName,
+			Usage:   "Number of keys whose attributes (type, TTL, memory etc.) are looked up using single pipeline",
+			Value:   100,
+			EnvVars: envVars(consts.MetadataBatchSizeArgName),
+		},
+		&cli.IntFlag{
+			Name:    consts.MetadataWorkersArgName,
+			Usage:   "Number of pipelines looking up attributes of the keys concurrently on each node",
+			Value:   4,
+			EnvVars: envVars(consts.MetadataWorkersArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.TokenizerArgName,
+			Usage:   "How keys are split into prefixes, segment splits them after delimiters, char into characters and byte into bytes",
+			Value:   iterator.TokenizerSegment,
+			EnvVars: envVars(consts.TokenizerArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.DelimiterArgName,
+			Usage:   "Characters ending segments of the keys, used by the segment tokenizer",
+			Value:   iterator.DefaultDelimiters,
+			EnvVars: envVars(consts.DelimiterArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.PatternsArgName,
+			Usage:   "Replace segments of the keys looking like IDs (numbers, UUIDs, hashes etc.) with placeholders e.g. user:{int}",
+			EnvVars: envVars(consts.PatternsArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.DisplayArgName,
+			Usage:   "How keys are displayed, escaped shows non-printable bytes as \\x00, hex shows every byte in hex and raw as they are",
+			Value:   utils.DisplayEscaped,
+			EnvVars: envVars(consts.DisplayArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.RulesArgName,
+			Usage:   "YAML file of rules classifying keys by glob or regex into named groups with owner and TTL policy",
+			EnvVars: envVars(consts.RulesArgName),
+		},
+		&cli.StringFlag{
+			Name:    consts.CheckpointFileArgName,
+			Usage:   "File where SCAN cursors and scanned prefixes are regularly saved",
+			EnvVars: envVars(consts.CheckpointFileArgName),
+		},
+		&cli.DurationFlag{
+			Name:    consts.CheckpointIntervalArgName,
+			Usage:   "How often the checkpoint is saved",
+			Value:   time.Minute,
+			EnvVars: envVars(consts.CheckpointIntervalArgName),
+		},
+		&cli.BoolFlag{
+			Name:    consts.ResumeArgName,
+			Usage:   "Resume the scan from the checkpoint file",
+			EnvVars: envVars(consts.ResumeArgName),
+		},
+	}
+}
+
+// ScanFlags returns flags describing how to connect to redis and scan it, shared by all the commands.
+func ScanFlags() []cli.Flag {
+	return append(connectionFlags(), scanFlags()...)
+}
+
+// PrintFlags returns flags of the print command.
+func PrintFlags() []cli.Flag {
+	return append(ScanFlags(), &cli.StringFlag{
+		Name:    consts.ReportArgName,
+		Usage:   "Print given report instead of all the prefixes, no-ttl lists prefixes where most keys never expire",
+		EnvVars: envVars(consts.ReportArgName),
+	})
+}
+
+// BigKeysFlags returns flags of the bigkeys command.
+func BigKeysFlags() []cli.Flag {
+	return append(
+		ScanFlags(),
+		&cli.IntFlag{
+			Name:    consts.TopKeysArgName,
+			Usage:   "Number of largest keys shown for each prefix",
+			Value:   10,
+			EnvVars: envVars(consts.TopKeysArgName),
+		},
+		&cli.IntFlag{
+			Name:    consts.GroupDepthArgName,
+			Usage:   "Number of prefix levels used to group the keys",
+			Value:   1,
+			EnvVars: envVars(consts.GroupDepthArgName),
+		},
+	)
+}
+
+// CheckFlags returns flags of the check command.
+func CheckFlags() []cli.Flag {
+	return append(ScanFlags(), &cli.StringFlag{
+		Name:     consts.SchemaArgName,
+		Usage:    "YAML file declaring allowed key patterns along with their max key counts, TTL policy and types",
+		Required: true,
+		EnvVars:  envVars(consts.SchemaArgName),
+	})
+}
+
+// LintFlags returns flags of the lint command.
+func LintFlags() []cli.Flag {
+	return append(ScanFlags(), &cli.IntFlag{
+		Name:    consts.MaxKeyLengthArgName,
+		Usage:   "Length in bytes above which keys are reported as overlong",
+		Value:   256,
+		EnvVars: envVars(consts.MaxKeyLengthArgName),
+	})
+}
+
+// GetConnectionOptions builds redis connection options from the parsed command line flags.
+func GetConnectionOptions(c *cli.Context) redisscanner.ConnectionOptions {
+	db := -1
+	if c.IsSet(consts.DBArgName) {
+		db = c.Int(consts.DBArgName)
+	}
+
+	return redisscanner.ConnectionOptions{
+		URL:                  c.String(consts.RedisURLArgName),
+		ClusterMode:          c.Bool(consts.ClusterModeArgName),
+		SentinelAddrs:        c.StringSlice(consts.SentinelAddrsArgName),
+		MasterName:           c.String(consts.MasterNameArgName),
+		PreferReplica:        c.Bool(consts.PreferReplicaArgName),
+		AllowPrimaryFallback: c.Bool(consts.AllowPrimaryFallbackArgName),
+		AllDatabases:         c.Bool(consts.AllDatabasesArgName),
+		DB:                   db,
+		Username:             c.String(consts.UsernameArgName),
+		Password:             c.String(consts.PasswordArgName),
+		ConnectTimeout:       c.Duration(consts.ConnectTimeoutArgName),
+		ReadTimeout:          c.Duration(consts.ReadTimeoutArgName),
+		TLS: redisscanner.TLSOptions{
+			Enabled:    c.Bool(consts.TLSArgName),
+			CACert:     c.String(consts.TLSCACertArgName),
+			Cert:       c.String(consts.TLSCertArgName),
+			Key:        c.String(consts.TLSKeyArgName),
+			ServerName: c.String(consts.TLSServerNameArgName),
+			SkipVerify: c.Bool(consts.TLSSkipVerifyArgName),
+		},
+	}
+}
+
+// GetScanOptions builds SCAN options from the parsed command line flags.
+func GetScanOptions(c *cli.Context) redisscanner.ScanOptions {
+	return redisscanner.ScanOptions{
+		Pattern:           c.String(consts.ScanPattern),
+		BatchSize:         c.Int64(consts.ScanBatchSizeArgName),
+		Type:              c.String(consts.ScanTypeArgName),
+		SampleFraction:    c.Float64(consts.SampleFractionArgName),
+		SampleMethod:      c.String(consts.SampleMethodArgName),
+		MaxRetries:        c.Int(consts.ScanRetriesArgName),
+		KeyTypes:          c.Bool(consts.KeyTypesArgName),
+		TTL:               c.Bool(consts.TTLArgName),
+		ObjectStats:       c.Bool(consts.ObjectStatsArgName),
+		MemoryUsage:       c.Bool(consts.MemoryUsageArgName),
+		MemorySampleRate:  c.Float64(consts.MemorySampleRateArgName),
+		MetadataBatchSize: c.Int(consts.MetadataBatchSizeArgName),
+		MetadataWorkers:   c.Int(consts.MetadataWorkersArgName),
+		Throttle: redisscanner.ThrottleOptions{
+			MaxOpsPerSecond:    c.Float64(consts.MaxOpsArgName),
+			Adaptive:           c.Bool(consts.AdaptiveThrottleArgName),
+			LatencyThreshold:   c.Duration(consts.LatencyThresholdArgName),
+			ServerOpsThreshold: c.Int64(consts.ServerOpsThresholdArgName),
+		},
+	}
+}
+
+// GetTokenizer returns tokenizer splitting the keys into edges of the trie as per the command line flags.
+// In case patterns are requested, keys are generalized into patterns before being split.
+func GetTokenizer(c *cli.Context) (iterator.Tokenizer, error) {
+	delimiters := c.String(consts.DelimiterArgName)
+	tokenizer, err := iterator.NewTokenizer(c.String(consts.TokenizerArgName), delimiters)
+	if err != nil || !c.Bool(consts.PatternsArgName) {
+		return tokenizer, err
+	}
+
+	return func(key string) interface{} {
+		return tokenizer(pattern.Generalize(key, delimiters))
+	}, nil
+}
+
+// GetCheckpointSettings returns settings of the scan which must stay the same when it's resumed from checkpoint.
+func GetCheckpointSettings(c *cli.Context, scanOptions redisscanner.ScanOptions) redisscanner.CheckpointSettings {
+	return redisscanner.CheckpointSettings{
+		Pattern:    scanOptions.Pattern,
+		Type:       scanOptions.Type,
+		Tokenizer:  c.String(consts.TokenizerArgName),
+		Delimiters: c.String(consts.DelimiterArgName),
+		Patterns:   c.Bool(consts.PatternsArgName),
+	}
+}
+
+// GetKeyFormatter returns formatter displaying the keys as per the command line flags.
+func GetKeyFormatter(c *cli.Context) (utils.KeyFormatter, error) {
+	return utils.NewKeyFormatter(c.String(consts.DisplayArgName))
+}
+
+// GetRuleSet loads classification rules given by command line flags, nil if no rules file is given.
+func GetRuleSet(c *cli.Context, scanOptions *redisscanner.ScanOptions) (*rules.RuleSet, error) {
+	path := c.String(consts.RulesArgName)
+	if path == "" {
+		return nil, nil
+	}
+	return loadRuleSet(path, scanOptions)
+}
+
+// GetSchema loads keyspace schema of the check command, which uses format of the rules file.
+func GetSchema(c *cli.Context, scanOptions *redisscanner.ScanOptions) (*rules.RuleSet, error) {
+	return loadRuleSet(c.String(consts.SchemaArgName), scanOptions)
+}
+
+// loadRuleSet loads the rules and adds them to collectors of the scan options,
+// along with collection of the key attributes they depend upon.
+func loadRuleSet(path string, scanOptions *redisscanner.ScanOptions) (*rules.RuleSet, error) {
+	ruleSet, err := rules.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	scanOptions.Collectors = append(scanOptions.Collectors, ruleSet)
+	if ruleSet.RequiresTTL() {
+		scanOptions.TTL = true
+	}
+	if ruleSet.RequiresTypes() {
+		scanOptions.KeyTypes = true
+	}
+	return ruleSet, nil
+}
+
+// ValidateScanOptions returns error in case scan options can't be used along with other command line flags.
+func ValidateScanOptions(c *cli.Context, scanOptions redisscanner.ScanOptions) error {
+	if err := scanOptions.Validate(); err != nil {
+		return err
+	}
+
+	if scanOptions.IsSampled() && c.String(consts.CheckpointFileArgName) != "" {
+		return errors.New("Sampling can't be used along with checkpoint file")
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+package keystats
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Ashish-Bansal/redis-spectacles/internal/utils"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/addable"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/trie"
+)
+
+// KeyTypes are the redis data types whose counts are kept, last one counts every unknown type.
+var KeyTypes = []string{"string", "list", "set", "zset", "hash", "stream", "other"}
+
+const numKeyTypes = 7
+
+// TTLBuckets are upper bounds of the TTL histogram buckets, longer TTLs are counted in an extra last bucket.
+var TTLBuckets = []time.Duration{time.Minute, time.Hour, 24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour}
+
+var ttlBucketNames = []string{"<1m", "<1h", "<1d", "<7d", "<30d", ">=30d"}
+
+const numTTLBuckets = 6
+
+// noTTLThreshold is fraction of keys without expiry above which prefix is considered to be never expiring.
+const noTTLThreshold = 0.5
+
+// Encodings are the internal encodings of redis objects whose counts are kept, last one counts every unknown encoding.
+var Encodings = []string{
+	"embstr", "raw", "int", "ziplist", "listpack", "quicklist", "linkedlist",
+	"intset", "hashtable", "skiplist", "stream", "other",
+}
+
+const numEncodings = 12
+
+// idleBuckets are upper bounds of the idle time histogram buckets in seconds.
+var idleBuckets = []int64{60, 10 * 60, 60 * 60, 6 * 60 * 60, 24 * 60 * 60, 7 * 24 * 60 * 60, 30 * 24 * 60 * 60}
+
+var idleBucketNames = []string{"<1m", "<10m", "<1h", "<6h", "<1d", "<7d", "<30d", ">=30d"}
+
+// freqBuckets are upper bounds of the LFU access frequency histogram buckets.
+var freqBuckets = []int64{1, 2, 5, 10, 20, 50, 100}
+
+var freqBucketNames = []string{"0", "1", "2-4", "5-9", "10-19", "20-49", "50-99", ">=100"}
+
+const numAccessBuckets = 8
+
+// accessPercentiles are the percentiles shown for idle time and access frequency.
+var accessPercentiles = []int{50, 90, 99}
+
+// BigKey is a key along with size of its value, which is length for strings and number of elements otherwise.
+type BigKey struct {
+	Key  string
+	Type string
+	Size int64
+}
+
+// DescribeSize returns size of the key along with its unit e.g. "120 elements".
+func (bigKey BigKey) DescribeSize() string {
+	if bigKey.Type == "string" {
+		return utils.FormatBytes(bigKey.Size)
+	}
+	return fmt.Sprintf("%d elements", bigKey.Size)
+}
+
+// mergeBigKeys returns at most limit largest keys out of both the lists, which must be in descending order of size.
+func mergeBigKeys(first []BigKey, second []BigKey, limit int) []BigKey {
+	merged := make([]BigKey, 0, utils.Min(len(first)+len(second), limit))
+	for len(merged) < limit && (len(first) != 0 || len(second) != 0) {
+		if len(second) == 0 || (len(first) != 0 && first[0].Size >= second[0].Size) {
+			merged = append(merged, first[0])
+			first = first[1:]
+		} else {
+			merged = append(merged, second[0])
+			second = second[1:]
+		}
+	}
+	return merged
+}
+
+// RuleStats counts keys matching single classification rule.
+type RuleStats struct {
+	Keys int64
+
+	// TTLViolations is number of keys whose expiry doesn't follow TTL policy of the rule,
+	// TypeViolations is number of keys whose type isn't allowed by the rule.
+	TTLViolations  int64
+	TypeViolations int64
+}
+
+// mergeRuleStats returns sum of the stats of each rule, it allocates new slice so that neither input is modified.
+func mergeRuleStats(first []RuleStats, second []RuleStats) []RuleStats {
+	merged := make([]RuleStats, utils.Max(len(first), len(second)))
+	for _, rules := range [][]RuleStats{first, second} {
+		for index, ruleStats := range rules {
+			merged[index].Keys += ruleStats.Keys
+			merged[index].TTLViolations += ruleStats.TTLViolations
+			merged[index].TypeViolations += ruleStats.TypeViolations
+		}
+	}
+	return merged
+}
+
+// TypeIndex returns index of the key type inside KeyTypes.
+func TypeIndex(keyType string) int {
+	for index, knownType := range KeyTypes[:numKeyTypes-1] {
+		if knownType == keyType {
+			return index
+		}
+	}
+	return numKeyTypes - 1
+}
+
+// KeyStats aggregates attributes of the keys sharing a prefix. It's stored as value of the trie
+// nodes, so that each prefix knows stats of all the keys under it.
+type KeyStats struct {
+	// MeasuredKeys is number of keys whose memory usage was measured, Bytes is their total memory usage.
+	MeasuredKeys int64
+	Bytes        int64
+
+	// TypeCounts is number of keys of each type in KeyTypes, keys with unknown type aren't counted.
+	TypeCounts [numKeyTypes]int64
+
+	// NoTTLKeys is number of keys without expiry, TTLCounts is number of expiring keys in each of TTLBuckets.
+	NoTTLKeys int64
+	TTLCounts [numTTLBuckets]int64
+
+	// EncodingCounts is number of keys with each of Encodings.
+	EncodingCounts [numEncodings]int64
+
+	// IdleCounts is histogram of idle time of the keys, FreqCounts is histogram of their access frequency.
+	// Only one of them is filled depending on whether server evicts keys using LFU policy.
+	IdleCounts [numAccessBuckets]int64
+	FreqCounts [numAccessBuckets]int64
+
+	// BigKeys holds at most BigKeysLimit largest keys in descending order of size.
+	BigKeys      []BigKey
+	BigKeysLimit int
+
+	// Rules holds stats of the keys matching each classification rule, in the order of the rules.
+	Rules []RuleStats
+}
+
+func init() {
+	// Registered so that tries holding KeyStats can be checkpointed.
+	gob.Register(KeyStats{})
+}
+
+// Add returns sum of the two stats.
+func (stats KeyStats) Add(other addable.Addable) (addable.Addable, error) {
+	otherStats, ok := other.(KeyStats)
+	if !ok {
+		return nil, errors.New("KeyStats can only be added to KeyStats")
+	}
+
+	stats.MeasuredKeys += otherStats.MeasuredKeys
+	stats.Bytes += otherStats.Bytes
+	for index := range stats.TypeCounts {
+		stats.TypeCounts[index] += otherStats.TypeCounts[index]
+	}
+	stats.NoTTLKeys += otherStats.NoTTLKeys
+	for index := range stats.TTLCounts {
+		stats.TTLCounts[index] += otherStats.TTLCounts[index]
+	}
+	for index := range stats.EncodingCounts {
+		stats.EncodingCounts[index] += otherStats.EncodingCounts[index]
+	}
+	for index := range stats.IdleCounts {
+		stats.IdleCounts[index] += otherStats.IdleCounts[index]
+		stats.FreqCounts[index] += otherStats.FreqCounts[index]
+	}
+	if len(otherStats.BigKeys) != 0 {
+		stats.BigKeysLimit = utils.Max(stats.BigKeysLimit, otherStats.BigKeysLimit)
+		stats.BigKeys = mergeBigKeys(stats.BigKeys, otherStats.BigKeys, stats.BigKeysLimit)
+	}
+	if len(otherStats.Rules) != 0 {
+		stats.Rules = mergeRuleStats(stats.Rules, otherStats.Rules)
+	}
+	return stats, nil
+}
+
+// EstimatedBytes extrapolates memory usage of all the keys from the measured ones.
+// It's exact in case every key was measured.
+func (stats KeyStats) EstimatedBytes(keyCount int) int64 {
+	if stats.MeasuredKeys == 0 {
+		return 0
+	}
+	return int64(float64(stats.Bytes) * float64(keyCount) / float64(stats.MeasuredKeys))
+}
+
+// SetType records type of the single key.
+func (stats *KeyStats) SetType(keyType string) {
+	stats.TypeCounts[TypeIndex(keyType)] = 1
+}
+
+// DescribeTypes returns count of keys per type in descending order e.g. "hash 80, string 20".
+func (stats KeyStats) DescribeTypes() string {
+	indexes := make([]int, 0)
+	for index, count := range stats.TypeCounts {
+		if count > 0 {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.SliceStable(indexes, func(i int, j int) bool {
+		return stats.TypeCounts[indexes[i]] > stats.TypeCounts[indexes[j]]
+	})
+
+	parts := make([]string, 0)
+	for _, index := range indexes {
+		parts = append(parts, fmt.Sprintf("%s %d", KeyTypes[index], stats.TypeCounts[index]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SetTTL records TTL of the single key, negative TTL means that key never expires.
+func (stats *KeyStats) SetTTL(ttl time.Duration) {
+	if ttl < 0 {
+		stats.NoTTLKeys = 1
+		return
+	}
+
+	bucket := len(TTLBuckets)
+	for index, upperBound := range TTLBuckets {
+		if ttl < upperBound {
+			bucket = index
+			break
+		}
+	}
+	stats.TTLCounts[bucket] = 1
+}
+
+// SetSize records size of the single key, at most limit largest keys are kept once stats are added up.
+func (stats *KeyStats) SetSize(key string, keyType string, size int64, limit int) {
+	stats.BigKeys = []BigKey{{Key: key, Type: keyType, Size: size}}
+	stats.BigKeysLimit = limit
+}
+
+// TTLKeys returns number of keys whose TTL is known.
+func (stats KeyStats) TTLKeys() int64 {
+	total := stats.NoTTLKeys
+	for _, count := range stats.TTLCounts {
+		total += count
+	}
+	return total
+}
+
+// NoTTLRatio returns fraction of the keys with known TTL which never expire.
+func (stats KeyStats) NoTTLRatio() float64 {
+	ttlKeys := stats.TTLKeys()
+	if ttlKeys == 0 {
+		return 0
+	}
+	return float64(stats.NoTTLKeys) / float64(ttlKeys)
+}
+
+// MostlyNoTTL returns whether most of the keys with known TTL never expire.
+func (stats KeyStats) MostlyNoTTL() bool {
+	return stats.NoTTLRatio() > noTTLThreshold
+}
+
+// DescribeTTLs returns TTL histogram of the keys e.g. "no expiry 80, <1h 15, <1d 5".
+func (stats KeyStats) DescribeTTLs() string {
+	parts := make([]string, 0)
+	if stats.NoTTLKeys > 0 {
+		parts = append(parts, fmt.Sprintf("no expiry %d", stats.NoTTLKeys))
+	}
+	for index, count := range stats.TTLCounts {
+		if count > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", ttlBucketNames[index], count))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SetEncoding records encoding of the single key.
+func (stats *KeyStats) SetEncoding(encoding string) {
+	index := numEncodings - 1
+	for encodingIndex, knownEncoding := range Encodings[:numEncodings-1] {
+		if knownEncoding == encoding {
+			index = encodingIndex
+			break
+		}
+	}
+	stats.EncodingCounts[index] = 1
+}
+
+// DescribeEncodings returns count of keys per encoding e.g. "ziplist 80, hashtable 20".
+func (stats KeyStats) DescribeEncodings() string {
+	parts := make([]string, 0)
+	for index, count := range stats.EncodingCounts {
+		if count > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", Encodings[index], count))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SetIdleTime records time since the single key was last accessed.
+func (stats *KeyStats) SetIdleTime(idleTime time.Duration) {
+	stats.IdleCounts[bucketIndex(idleBuckets, int64(idleTime/time.Second))] = 1
+}
+
+// SetFreq records logarithmic access frequency counter of the single key, as kept by LFU policy.
+func (stats *KeyStats) SetFreq(freq int64) {
+	stats.FreqCounts[bucketIndex(freqBuckets, freq)] = 1
+}
+
+// DescribeAccess returns percentiles of idle time or access frequency of the keys,
+// e.g. "idle p50 <1h, p90 <7d, p99 >=30d".
+func (stats KeyStats) DescribeAccess() string {
+	if description := describePercentiles(stats.IdleCounts[:], idleBucketNames); description != "" {
+		return "idle " + description
+	}
+	if description := describePercentiles(stats.FreqCounts[:], freqBucketNames); description != "" {
+		return "freq " + description
+	}
+	return ""
+}
+
+// bucketIndex returns index of the histogram bucket of the value given upper bounds of the buckets.
+// Values beyond the last bound belong to an extra last bucket.
+func bucketIndex(bounds []int64, value int64) int {
+	for index, upperBound := range bounds {
+		if value < upperBound {
+			return index
+		}
+	}
+	return len(bounds)
+}
+
+// describePercentiles returns histogram bucket in which each of accessPercentiles lies, empty if histogram is empty.
+func describePercentiles(counts []int64, bucketNames []string) string {
+	total := int64(0)
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0)
+	for _, percentile := range accessPercentiles {
+		threshold := float64(total) * float64(percentile) / 100
+		cumulative := int64(0)
+		for index, count := range counts {
+			cumulative += count
+			if float64(cumulative) >= threshold {
+				parts = append(parts, fmt.Sprintf("p%d %s", percentile, bucketNames[index]))
+				break
+			}
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SetRule records stats of the single key matching classification rule of given index.
+func (stats *KeyStats) SetRule(index int, ruleStats RuleStats) {
+	stats.Rules = make([]RuleStats, index+1)
+	stats.Rules[index] = ruleStats
+}
+
+// FromValue returns stats held by the trie value, zero stats if there's none.
+func FromValue(value interface{}) KeyStats {
+	stats, _ := value.(KeyStats)
+	return stats
+}
+
+// OfNode returns stats of all the keys passing through the trie node.
+func OfNode(node *trie.Node) KeyStats {
+	return FromValue(node.Value())
+}
@@ -0,0 +1,189 @@
+package keystats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func bigKeys(sizes ...int64) []BigKey {
+	keys := make([]BigKey, 0, len(sizes))
+	for _, size := range sizes {
+		keys = append(keys, BigKey{Key: "key", Type: "hash", Size: size})
+	}
+	return keys
+}
+
+func sizes(keys []BigKey) []int64 {
+	keySizes := make([]int64, 0, len(keys))
+	for _, key := range keys {
+		keySizes = append(keySizes, key.Size)
+	}
+	return keySizes
+}
+
+func TestMergeBigKeys(t *testing.T) {
+	testCases := []struct {
+		first    []int64
+		second   []int64
+		limit    int
+		expected []int64
+	}{
+		{[]int64{9, 5, 1}, []int64{7, 3}, 10, []int64{9, 7, 5, 3, 1}},
+		{[]int64{9, 5, 1}, []int64{7, 3}, 3, []int64{9, 7, 5}},
+		{[]int64{4, 4}, []int64{4}, 2, []int64{4, 4}},
+		{nil, []int64{7, 3}, 1, []int64{7}},
+		{[]int64{9}, nil, 5, []int64{9}},
+		{[]int64{9}, []int64{7}, 0, []int64{}},
+		{nil, nil, 5, []int64{}},
+	}
+
+	for _, testCase := range testCases {
+		merged := mergeBigKeys(bigKeys(testCase.first...), bigKeys(testCase.second...), testCase.limit)
+		if actual := sizes(merged); !reflect.DeepEqual(testCase.expected, actual) {
+			t.Errorf(
+				"Incorrect merge of %v and %v limited to %d. Expected %v, got %v",
+				testCase.first,
+				testCase.second,
+				testCase.limit,
+				testCase.expected,
+				actual,
+			)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	first := KeyStats{MeasuredKeys: 1, Bytes: 100}
+	first.SetType("hash")
+	first.SetTTL(-1)
+	first.SetEncoding("listpack")
+	first.SetIdleTime(time.Hour)
+	first.SetSize("user:1", "hash", 30, 2)
+	first.SetRule(1, RuleStats{Keys: 1, TTLViolations: 1})
+
+	second := KeyStats{MeasuredKeys: 2, Bytes: 300}
+	second.SetType("string")
+	second.SetTTL(time.Minute)
+	second.SetEncoding("embstr")
+	second.SetIdleTime(time.Second)
+	second.BigKeys = bigKeys(50, 20, 10)
+	second.BigKeysLimit = 3
+	second.SetRule(0, RuleStats{Keys: 1, TypeViolations: 1})
+
+	sum, err := first.Add(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := sum.(KeyStats)
+
+	if stats.MeasuredKeys != 3 || stats.Bytes != 400 {
+		t.Errorf("Incorrect memory usage. Expected %d bytes of %d keys, got %d bytes of %d keys", 400, 3, stats.Bytes, stats.MeasuredKeys)
+	}
+	if description := stats.DescribeTypes(); description != "string 1, hash 1" {
+		t.Errorf("Incorrect types %q", description)
+	}
+	if description := stats.DescribeTTLs(); description != "no expiry 1, <1h 1" {
+		t.Errorf("Incorrect TTLs %q", description)
+	}
+	if description := stats.DescribeEncodings(); description != "embstr 1, listpack 1" {
+		t.Errorf("Incorrect encodings %q", description)
+	}
+	if description := stats.DescribeAccess(); description != "idle p50 <1m, p90 <6h, p99 <6h" {
+		t.Errorf("Incorrect access %q", description)
+	}
+	if stats.BigKeysLimit != 3 || !reflect.DeepEqual([]int64{50, 30, 20}, sizes(stats.BigKeys)) {
+		t.Errorf("Incorrect big keys %v limited to %d", sizes(stats.BigKeys), stats.BigKeysLimit)
+	}
+
+	expectedRules := []RuleStats{{Keys: 1, TypeViolations: 1}, {Keys: 1, TTLViolations: 1}}
+	if !reflect.DeepEqual(expectedRules, stats.Rules) {
+		t.Errorf("Incorrect rule stats. Expected %+v, got %+v", expectedRules, stats.Rules)
+	}
+	if len(first.Rules) != 2 || first.Rules[0].Keys != 0 || len(first.BigKeys) != 1 {
+		t.Error("Adding stats must not modify the operands")
+	}
+
+	if _, err := first.Add(nil); err == nil {
+		t.Error("Adding stats of different type must fail")
+	}
+}
+
+func TestSetTTL(t *testing.T) {
+	testCases := []struct {
+		ttl         time.Duration
+		description string
+	}{
+		{-1, "no expiry 1"},
+		{0, "<1m 1"},
+		{59 * time.Second, "<1m 1"},
+		{time.Minute, "<1h 1"},
+		{23 * time.Hour, "<1d 1"},
+		{24 * time.Hour, "<7d 1"},
+		{29 * 24 * time.Hour, "<30d 1"},
+		{30 * 24 * time.Hour, ">=30d 1"},
+		{365 * 24 * time.Hour, ">=30d 1"},
+	}
+
+	for _, testCase := range testCases {
+		stats := KeyStats{}
+		stats.SetTTL(testCase.ttl)
+		if description := stats.DescribeTTLs(); description != testCase.description || stats.TTLKeys() != 1 {
+			t.Errorf(
+				"Incorrect bucket of TTL %v. Expected %q, got %q with %d keys",
+				testCase.ttl,
+				testCase.description,
+				description,
+				stats.TTLKeys(),
+			)
+		}
+	}
+}
+
+func TestDescribePercentiles(t *testing.T) {
+	testCases := []struct {
+		counts      []int64
+		description string
+	}{
+		{[]int64{0, 0, 0, 0, 0, 0, 0, 0}, ""},
+		{[]int64{2, 0, 0, 0, 0, 0, 0, 0}, "p50 <1m, p90 <1m, p99 <1m"},
+		{[]int64{50, 40, 0, 0, 0, 0, 0, 10}, "p50 <1m, p90 <10m, p99 >=30d"},
+		{[]int64{0, 0, 1, 0, 0, 0, 0, 1}, "p50 <1h, p90 >=30d, p99 >=30d"},
+		{[]int64{0, 0, 0, 0, 0, 0, 0, 7}, "p50 >=30d, p90 >=30d, p99 >=30d"},
+	}
+
+	for _, testCase := range testCases {
+		if description := describePercentiles(testCase.counts, idleBucketNames); description != testCase.description {
+			t.Errorf("Incorrect percentiles of %v. Expected %q, got %q", testCase.counts, testCase.description, description)
+		}
+	}
+}
+
+func TestEstimatedBytes(t *testing.T) {
+	testCases := []struct {
+		measuredKeys int64
+		bytes        int64
+		keyCount     int
+		expected     int64
+	}{
+		{0, 0, 100, 0},
+		{10, 1000, 10, 1000},
+		{10, 1000, 100, 10000},
+		{3, 100, 10, 333},
+		{4, 400, 0, 0},
+	}
+
+	for _, testCase := range testCases {
+		stats := KeyStats{MeasuredKeys: testCase.measuredKeys, Bytes: testCase.bytes}
+		if estimate := stats.EstimatedBytes(testCase.keyCount); estimate != testCase.expected {
+			t.Errorf(
+				"Incorrect estimate for %d keys given %d bytes of %d keys. Expected %d, got %d",
+				testCase.keyCount,
+				testCase.bytes,
+				testCase.measuredKeys,
+				testCase.expected,
+				estimate,
+			)
+		}
+	}
+}
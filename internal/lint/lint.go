@@ -0,0 +1,288 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/iterator"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/trie"
+)
+
+// Kinds of the findings.
+const (
+	InconsistentDelimiters = "inconsistent-delimiters"
+	CaseConflict           = "case-conflict"
+	LongKey                = "long-key"
+	BinaryKey              = "binary"
+	WhitespaceKey          = "whitespace"
+	TrailingDelimiter      = "trailing-delimiter"
+)
+
+// MaxSampleKeys is maximum number of keys listed for every finding.
+const MaxSampleKeys = 5
+
+// Options controls what's considered a naming problem.
+type Options struct {
+	// Delimiters are the characters expected to separate segments of the keys.
+	Delimiters string
+	// MaxKeyLength is length in bytes above which key is considered overlong.
+	MaxKeyLength int
+	// Branches are the top-level prefixes under which keys of the shards were inserted e.g. "db1/",
+	// they aren't part of the key names, so the checks are done on the rest of the keys.
+	Branches []string
+}
+
+// Finding is a naming problem shared by Count keys, some of which are listed in SampleKeys.
+type Finding struct {
+	Kind       string
+	Message    string
+	Count      int
+	SampleKeys []string
+}
+
+func (finding *Finding) add(key string, count int) {
+	finding.Count += count
+	if len(finding.SampleKeys) < MaxSampleKeys {
+		finding.SampleKeys = append(finding.SampleKeys, key)
+	}
+}
+
+// Lint checks names of the keys in the condensed trie and returns findings ordered by kind,
+// ones affecting most keys first.
+func Lint(node *trie.Node, options Options) []*Finding {
+	linter := &linter{
+		options:    options,
+		keyChecks:  make(map[string]*Finding),
+		rootDelims: make(map[string]map[string]*Finding),
+		findings:   make([]*Finding, 0),
+	}
+	walkKeys(node, "", linter.checkKey)
+	linter.checkCaseConflicts(node, "")
+
+	for _, kind := range []string{LongKey, BinaryKey, WhitespaceKey, TrailingDelimiter} {
+		if finding, ok := linter.keyChecks[kind]; ok {
+			linter.findings = append(linter.findings, finding)
+		}
+	}
+	linter.addDelimiterFindings()
+
+	kindOrder := map[string]int{
+		InconsistentDelimiters: 0,
+		CaseConflict:           1,
+		LongKey:                2,
+		BinaryKey:              3,
+		WhitespaceKey:          4,
+		TrailingDelimiter:      5,
+	}
+	sort.SliceStable(linter.findings, func(i int, j int) bool {
+		first, second := linter.findings[i], linter.findings[j]
+		if first.Kind != second.Kind {
+			return kindOrder[first.Kind] < kindOrder[second.Kind]
+		}
+		return first.Count > second.Count
+	})
+	return linter.findings
+}
+
+type linter struct {
+	options Options
+
+	// keyChecks holds findings of the checks done on every key on its own.
+	keyChecks map[string]*Finding
+
+	// rootDelims holds keys grouped by their lower cased root word and the delimiter following it.
+	rootDelims map[string]map[string]*Finding
+
+	findings []*Finding
+}
+
+// walkKeys calls callback with every key of the trie along with number of times it was inserted.
+func walkKeys(node *trie.Node, prefix string, callback func(key string, count int)) {
+	if node.DataCount != 0 {
+		callback(prefix, node.DataCount)
+	}
+	for _, edge := range node.GetEdges() {
+		walkKeys(node.Edges[edge], prefix+edge.Prefix.(string), callback)
+	}
+}
+
+func (linter *linter) keyCheck(kind string, message string) *Finding {
+	finding, ok := linter.keyChecks[kind]
+	if !ok {
+		finding = &Finding{Kind: kind, Message: message}
+		linter.keyChecks[kind] = finding
+	}
+	return finding
+}
+
+// stripBranch returns name of the key without the shard branch it was inserted under.
+func (linter *linter) stripBranch(key string) string {
+	for _, branch := range linter.options.Branches {
+		if strings.HasPrefix(key, branch) {
+			return key[len(branch):]
+		}
+	}
+	return key
+}
+
+// checkKey checks name of the key, samples of the findings keep the shard branch so that key can be located.
+func (linter *linter) checkKey(key string, count int) {
+	name := linter.stripBranch(key)
+	if linter.options.MaxKeyLength > 0 && len(name) > linter.options.MaxKeyLength {
+		message := fmt.Sprintf("Keys longer than %d bytes", linter.options.MaxKeyLength)
+		linter.keyCheck(LongKey, message).add(key, count)
+	}
+	if isBinary(name) {
+		linter.keyCheck(BinaryKey, "Keys with non-printable bytes or invalid UTF-8").add(key, count)
+	}
+	if strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		linter.keyCheck(WhitespaceKey, "Keys with whitespace").add(key, count)
+	}
+	if lastRune, _ := utf8.DecodeLastRuneInString(name); strings.ContainsRune(linter.options.Delimiters, lastRune) {
+		linter.keyCheck(TrailingDelimiter, "Keys ending with a delimiter").add(key, count)
+	}
+
+	root, delimiter := splitRoot(name)
+	if delimiter == "" {
+		return
+	}
+	lowerRoot := strings.ToLower(root)
+	if _, ok := linter.rootDelims[lowerRoot]; !ok {
+		linter.rootDelims[lowerRoot] = make(map[string]*Finding)
+	}
+	if _, ok := linter.rootDelims[lowerRoot][delimiter]; !ok {
+		linter.rootDelims[lowerRoot][delimiter] = &Finding{}
+	}
+	linter.rootDelims[lowerRoot][delimiter].add(key, count)
+}
+
+// splitRoot returns the leading word of the key along with the character following it,
+// empty delimiter if there's no word or it isn't followed by a punctuation e.g. "user" and ":" for "user:1".
+func splitRoot(key string) (string, string) {
+	end := strings.IndexFunc(key, func(character rune) bool {
+		return !unicode.IsLetter(character)
+	})
+	if end <= 0 {
+		return key, ""
+	}
+
+	delimiter, _ := utf8.DecodeRuneInString(key[end:])
+	if !unicode.IsPunct(delimiter) && !unicode.IsSymbol(delimiter) {
+		return key[:end], ""
+	}
+	return key[:end], string(delimiter)
+}
+
+// isBinary returns whether key isn't valid UTF-8 or contains control characters.
+func isBinary(key string) bool {
+	if !utf8.ValidString(key) {
+		return true
+	}
+	return strings.IndexFunc(key, unicode.IsControl) >= 0
+}
+
+// addDelimiterFindings reports roots whose keys continue with more than one delimiter e.g. "user:1" and "user_1".
+func (linter *linter) addDelimiterFindings() {
+	for root, delimiters := range linter.rootDelims {
+		if len(delimiters) < 2 {
+			continue
+		}
+
+		usedDelimiters := make([]string, 0, len(delimiters))
+		for delimiter := range delimiters {
+			usedDelimiters = append(usedDelimiters, delimiter)
+		}
+		sort.SliceStable(usedDelimiters, func(i int, j int) bool {
+			return delimiters[usedDelimiters[i]].Count > delimiters[usedDelimiters[j]].Count
+		})
+
+		finding := &Finding{Kind: InconsistentDelimiters}
+		parts := make([]string, 0, len(usedDelimiters))
+		for _, delimiter := range usedDelimiters {
+			delimiterKeys := delimiters[delimiter]
+			parts = append(parts, fmt.Sprintf("%q in %d keys", delimiter, delimiterKeys.Count))
+			finding.Count += delimiterKeys.Count
+			// Samples of every delimiter are listed, so that the difference can be seen.
+			finding.SampleKeys = append(finding.SampleKeys, delimiterKeys.SampleKeys[0])
+		}
+		finding.Message = fmt.Sprintf("Root %q is followed by different delimiters: %s", root, strings.Join(parts, ", "))
+		if len(finding.SampleKeys) > MaxSampleKeys {
+			finding.SampleKeys = finding.SampleKeys[:MaxSampleKeys]
+		}
+		linter.findings = append(linter.findings, finding)
+	}
+}
+
+// checkCaseConflicts reports sibling prefixes whose first segments differ only in case e.g. "user:" and "User:".
+func (linter *linter) checkCaseConflicts(node *trie.Node, prefix string) {
+	variants := make(map[string][]*trie.Edge)
+	order := make([]string, 0)
+	for _, edge := range node.GetEdges() {
+		segment := linter.firstSegment(edge.Prefix.(string))
+		// Case of the binary segments is meaningless, they're reported as binary keys instead.
+		if isBinary(segment) {
+			continue
+		}
+		lowerSegment := strings.ToLower(segment)
+		if _, ok := variants[lowerSegment]; !ok {
+			order = append(order, lowerSegment)
+		}
+		variants[lowerSegment] = append(variants[lowerSegment], edge)
+	}
+
+	for _, lowerSegment := range order {
+		edges := variants[lowerSegment]
+		segments := make(map[string]bool)
+		for _, edge := range edges {
+			segments[linter.firstSegment(edge.Prefix.(string))] = true
+		}
+		if len(segments) < 2 {
+			continue
+		}
+
+		finding := &Finding{Kind: CaseConflict}
+		names := make([]string, 0, len(segments))
+		for _, edge := range edges {
+			segment := linter.firstSegment(edge.Prefix.(string))
+			if segments[segment] {
+				names = append(names, fmt.Sprintf("%q", prefix+segment))
+				delete(segments, segment)
+			}
+
+			childNode := node.Edges[edge]
+			finding.Count += childNode.Count()
+			if len(finding.SampleKeys) < MaxSampleKeys {
+				finding.SampleKeys = append(finding.SampleKeys, firstKey(childNode, prefix+edge.Prefix.(string)))
+			}
+		}
+		finding.Message = "Sibling prefixes differ only in case: " + strings.Join(names, ", ")
+		linter.findings = append(linter.findings, finding)
+	}
+
+	for _, edge := range node.GetEdges() {
+		linter.checkCaseConflicts(node.Edges[edge], prefix+edge.Prefix.(string))
+	}
+}
+
+// firstSegment returns prefix up to and including its first delimiter, whole prefix if it has none.
+func (linter *linter) firstSegment(prefix string) string {
+	it, _ := iterator.NewIterator(iterator.Segments{Str: prefix, Delimiters: linter.options.Delimiters})
+	if !it.HasNext() {
+		return prefix
+	}
+	segment, _ := it.Next()
+	return segment.(string)
+}
+
+// firstKey returns the first key passing through the node in order of the prefixes.
+func firstKey(node *trie.Node, prefix string) string {
+	for node.DataCount == 0 && len(node.Edges) != 0 {
+		edge := node.GetEdges()[0]
+		prefix += edge.Prefix.(string)
+		node = node.Edges[edge]
+	}
+	return prefix
+}
@@ -0,0 +1,150 @@
+package lint
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/iterator"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/trie"
+)
+
+var testOptions = Options{Delimiters: iterator.DefaultDelimiters, MaxKeyLength: 16}
+
+func lintKeys(keys []string, options Options) []*Finding {
+	node := trie.NewNode()
+	for _, key := range keys {
+		node.Insert(iterator.Segments{Str: key, Delimiters: options.Delimiters})
+	}
+	node.Condense()
+	return Lint(node, options)
+}
+
+func findingsOfKind(findings []*Finding, kind string) []*Finding {
+	kindFindings := make([]*Finding, 0)
+	for _, finding := range findings {
+		if finding.Kind == kind {
+			kindFindings = append(kindFindings, finding)
+		}
+	}
+	return kindFindings
+}
+
+func sortedSampleKeys(finding *Finding) []string {
+	keys := append([]string{}, finding.SampleKeys...)
+	sort.Strings(keys)
+	return keys
+}
+
+func TestInconsistentDelimiters(t *testing.T) {
+	findings := lintKeys([]string{"user:1", "user:2", "user_3", "order:1", "order:2"}, testOptions)
+
+	delimiterFindings := findingsOfKind(findings, InconsistentDelimiters)
+	if len(delimiterFindings) != 1 {
+		t.Fatalf("Expected single inconsistent delimiters finding, got %d", len(delimiterFindings))
+	}
+
+	finding := delimiterFindings[0]
+	expectedMessage := `Root "user" is followed by different delimiters: ":" in 2 keys, "_" in 1 keys`
+	if finding.Count != 3 || finding.Message != expectedMessage {
+		t.Errorf("Incorrect finding. Expected %d keys with message %q, got %+v", 3, expectedMessage, *finding)
+	}
+
+	expectedKeys := []string{"user:1", "user_3"}
+	if keys := sortedSampleKeys(finding); !reflect.DeepEqual(expectedKeys, keys) {
+		t.Errorf("Incorrect sample keys. Expected %q, got %q", expectedKeys, keys)
+	}
+}
+
+func TestCaseConflicts(t *testing.T) {
+	findings := lintKeys([]string{"user:1", "user:2", "User:3", "cart:user:1", "cart:USER:2", "order:1"}, testOptions)
+
+	caseFindings := findingsOfKind(findings, CaseConflict)
+	if len(caseFindings) != 2 {
+		t.Fatalf("Expected two case conflict findings, got %d", len(caseFindings))
+	}
+
+	counts := []int{caseFindings[0].Count, caseFindings[1].Count}
+	sort.Ints(counts)
+	if !reflect.DeepEqual([]int{2, 3}, counts) {
+		t.Errorf("Incorrect number of keys of case conflicts. Expected %v, got %v", []int{2, 3}, counts)
+	}
+
+	if findings := lintKeys([]string{"bin:\xff\x01", "bin:\xff\x02"}, testOptions); len(findingsOfKind(findings, CaseConflict)) != 0 {
+		t.Error("Binary segments must not be reported as case conflicts")
+	}
+}
+
+func TestKeyChecks(t *testing.T) {
+	keys := []string{
+		"bin:\x00\xff",
+		"bin:\x00\xff:meta",
+		"session:very-long-identifier",
+		"session:very-long-identifier:1",
+		"user:",
+		"user:1",
+		"my key",
+		"my key:1",
+	}
+	findings := lintKeys(keys, testOptions)
+
+	testCases := []struct {
+		kind string
+		keys []string
+	}{
+		{BinaryKey, []string{"bin:\x00\xff", "bin:\x00\xff:meta"}},
+		{LongKey, []string{"session:very-long-identifier", "session:very-long-identifier:1"}},
+		{TrailingDelimiter, []string{"user:"}},
+		{WhitespaceKey, []string{"my key", "my key:1"}},
+	}
+
+	for _, testCase := range testCases {
+		kindFindings := findingsOfKind(findings, testCase.kind)
+		if len(kindFindings) != 1 {
+			t.Errorf("Expected single %s finding, got %d", testCase.kind, len(kindFindings))
+			continue
+		}
+
+		finding := kindFindings[0]
+		if keys := sortedSampleKeys(finding); finding.Count != len(testCase.keys) || !reflect.DeepEqual(testCase.keys, keys) {
+			t.Errorf(
+				"Incorrect %s finding. Expected keys %q, got %d keys with samples %q",
+				testCase.kind,
+				testCase.keys,
+				finding.Count,
+				keys,
+			)
+		}
+	}
+}
+
+func TestCleanKeys(t *testing.T) {
+	if findings := lintKeys([]string{"user:1", "user:2", "order:1:items"}, testOptions); len(findings) != 0 {
+		t.Errorf("Consistently named keys must have no findings, got %d", len(findings))
+	}
+}
+
+func TestShardBranches(t *testing.T) {
+	options := Options{Delimiters: iterator.DefaultDelimiters, MaxKeyLength: 6, Branches: []string{"db0/", "db1/"}}
+	node := trie.NewNode()
+	for branch, keys := range map[string][]string{"db0/": {"user:1", "user:2"}, "db1/": {"user_3"}} {
+		for _, key := range keys {
+			node.Insert(iterator.Branched{Branch: branch, Item: iterator.Segments{Str: key, Delimiters: options.Delimiters}})
+		}
+	}
+	node.Condense()
+	findings := Lint(node, options)
+
+	if len(findingsOfKind(findings, LongKey)) != 0 {
+		t.Error("Branch must not count towards length of the keys")
+	}
+
+	delimiterFindings := findingsOfKind(findings, InconsistentDelimiters)
+	if len(delimiterFindings) != 1 {
+		t.Fatalf("Expected single inconsistent delimiters finding, got %d", len(delimiterFindings))
+	}
+	expectedKeys := []string{"db0/user:1", "db1/user_3"}
+	if keys := sortedSampleKeys(delimiterFindings[0]); delimiterFindings[0].Count != 3 || !reflect.DeepEqual(expectedKeys, keys) {
+		t.Errorf("Incorrect finding. Expected 3 keys with samples %q, got %+v", expectedKeys, *delimiterFindings[0])
+	}
+}
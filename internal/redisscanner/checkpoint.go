@@ -0,0 +1,266 @@
+package redisscanner
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/trie"
+)
+
+// ShardCheckpoint is the position of the scan in single shard.
+type ShardCheckpoint struct {
+	Cursor      uint64
+	Completed   bool
+	KeysScanned int64
+
+	// PageKeys is number of keys of the page starting at Cursor which are already inserted into the trie,
+	// they are skipped on resume so that no key is inserted twice.
+	PageKeys int64
+}
+
+// CheckpointSettings are the options deciding which keys are scanned and how they are inserted into the trie.
+// Scan can be resumed only with the same settings, otherwise the cursors and the trie wouldn't match the scan.
+type CheckpointSettings struct {
+	Pattern    string
+	Type       string
+	Tokenizer  string
+	Delimiters string
+	Patterns   bool
+}
+
+// Checkpoint holds everything required to resume the scan i.e. cursor of every shard
+// along with the trie built from the keys scanned till those cursors.
+type Checkpoint struct {
+	Settings CheckpointSettings
+
+	// Shards are keyed by checkpointKey of the shard.
+	Shards map[string]*ShardCheckpoint
+	Trie   *trie.Node
+
+	// tracked maps shards being scanned to their checkpoints, it isn't saved.
+	tracked map[*Shard]*ShardCheckpoint
+}
+
+// NewCheckpoint returns empty checkpoint for the shards scanned with given settings.
+func NewCheckpoint(shards []*Shard, node *trie.Node, settings CheckpointSettings) *Checkpoint {
+	checkpoint := &Checkpoint{
+		Settings: settings,
+		Shards:   make(map[string]*ShardCheckpoint),
+		Trie:     node,
+		tracked:  make(map[*Shard]*ShardCheckpoint),
+	}
+	for _, shard := range shards {
+		shardCheckpoint := &ShardCheckpoint{}
+		checkpoint.Shards[shard.checkpointKey()] = shardCheckpoint
+		checkpoint.tracked[shard] = shardCheckpoint
+	}
+	return checkpoint
+}
+
+// checkpointKey identifies the shard in the checkpoint. Replica chosen for the primary may differ
+// between the runs, so shard is identified by address of its primary along with its database.
+func (shard *Shard) checkpointKey() string {
+	primaryAddr := shard.PrimaryAddr
+	if primaryAddr == "" {
+		primaryAddr = shard.Client.Options().Addr
+	}
+	return fmt.Sprintf("%s/db%d", primaryAddr, shard.Client.Options().DB)
+}
+
+// LoadCheckpoint reads checkpoint previously saved at the path.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	checkpoint := &Checkpoint{}
+	err = decodeCheckpoint(file, checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read checkpoint %s: %v", path, err)
+	}
+	return checkpoint, nil
+}
+
+// Save atomically writes checkpoint at the path.
+func (checkpoint *Checkpoint) Save(path string) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	err = encodeCheckpoint(file, checkpoint)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func encodeCheckpoint(writer io.Writer, checkpoint *Checkpoint) error {
+	encoder := gob.NewEncoder(writer)
+	if err := encoder.Encode(checkpoint.Settings); err != nil {
+		return err
+	}
+	if err := encoder.Encode(checkpoint.Shards); err != nil {
+		return err
+	}
+	return checkpoint.Trie.Encode(writer)
+}
+
+func decodeCheckpoint(reader io.Reader, checkpoint *Checkpoint) error {
+	// Both the decoders must share buffered reader, otherwise first one may consume bytes of the trie.
+	bufferedReader := bufio.NewReader(reader)
+	decoder := gob.NewDecoder(bufferedReader)
+	if err := decoder.Decode(&checkpoint.Settings); err != nil {
+		return err
+	}
+	if err := decoder.Decode(&checkpoint.Shards); err != nil {
+		return err
+	}
+
+	var err error
+	checkpoint.Trie, err = trie.Decode(bufferedReader)
+	return err
+}
+
+// Restore makes the shards resume scan from the checkpointed cursors. Shards and settings
+// must be the same which were used when the checkpoint was created.
+func (checkpoint *Checkpoint) Restore(shards []*Shard, settings CheckpointSettings) error {
+	if checkpoint.Settings != settings {
+		return fmt.Errorf(
+			"Checkpoint was created with settings %+v, but the scan uses %+v, resume with the same flags",
+			checkpoint.Settings,
+			settings,
+		)
+	}
+	if len(shards) != len(checkpoint.Shards) {
+		return fmt.Errorf("Checkpoint has %d shards, but %d shards were found", len(checkpoint.Shards), len(shards))
+	}
+
+	checkpoint.tracked = make(map[*Shard]*ShardCheckpoint)
+	for _, shard := range shards {
+		shardCheckpoint, ok := checkpoint.Shards[shard.checkpointKey()]
+		if !ok {
+			return fmt.Errorf("Shard %s not found in the checkpoint", shard.Describe())
+		}
+
+		shard.startCursor = shardCheckpoint.Cursor
+		shard.skipKeys = shardCheckpoint.PageKeys
+		shard.completed = shardCheckpoint.Completed
+		shard.keysScanned = shardCheckpoint.KeysScanned
+		checkpoint.tracked[shard] = shardCheckpoint
+	}
+	return nil
+}
+
+// Track records the key as inserted into the checkpointed trie. Cursor of the shard moves forward only
+// once the last key of the page is tracked, keys of the page tracked till then are counted instead,
+// so that checkpoint can be saved in the middle of the page without losing or duplicating keys on resume.
+func (checkpoint *Checkpoint) Track(record KeyRecord) {
+	shardCheckpoint := checkpoint.tracked[record.Shard]
+	shardCheckpoint.KeysScanned++
+	if !record.LastInPage {
+		shardCheckpoint.PageKeys++
+		return
+	}
+
+	shardCheckpoint.Cursor = record.Cursor
+	shardCheckpoint.PageKeys = 0
+	shardCheckpoint.Completed = record.Cursor == 0
+}
+
+// Checkpointer periodically saves the checkpoint while keys are being scanned.
+// Nil checkpointer is valid and does nothing, which is used when checkpointing is disabled.
+type Checkpointer struct {
+	path       string
+	interval   time.Duration
+	lastSave   time.Time
+	checkpoint *Checkpoint
+	err        error
+}
+
+// NewCheckpointer returns checkpointer saving the checkpoint at the path every interval.
+func NewCheckpointer(path string, interval time.Duration, checkpoint *Checkpoint) *Checkpointer {
+	return &Checkpointer{path: path, interval: interval, lastSave: time.Now(), checkpoint: checkpoint}
+}
+
+// Track records the key as inserted into the trie and saves the checkpoint if it's due.
+func (checkpointer *Checkpointer) Track(record KeyRecord) {
+	if checkpointer == nil {
+		return
+	}
+
+	checkpointer.checkpoint.Track(record)
+	if time.Since(checkpointer.lastSave) >= checkpointer.interval {
+		checkpointer.Save()
+	}
+}
+
+// Save writes the checkpoint immediately.
+func (checkpointer *Checkpointer) Save() {
+	if checkpointer == nil {
+		return
+	}
+
+	checkpointer.lastSave = time.Now()
+	err := checkpointer.checkpoint.Save(checkpointer.path)
+	if err != nil {
+		checkpointer.err = fmt.Errorf("Unable to save checkpoint %s: %v", checkpointer.path, err)
+	}
+}
+
+// Err returns error of the last failed save, scanning goes on even if checkpoint couldn't be saved.
+func (checkpointer *Checkpointer) Err() error {
+	if checkpointer == nil {
+		return nil
+	}
+	return checkpointer.err
+}
+
+// PrepareCheckpointing returns trie into which scanned keys must be inserted, along with checkpointer
+// saving it. While resuming, trie and shard cursors are restored from the checkpoint at the path,
+// which must have been created with the same settings.
+// Returned checkpointer is nil if path is empty.
+func PrepareCheckpointing(
+	shards []*Shard,
+	path string,
+	interval time.Duration,
+	resume bool,
+	settings CheckpointSettings,
+) (*trie.Node, *Checkpointer, error) {
+	if path == "" {
+		if resume {
+			return nil, nil, errors.New("Checkpoint file is required to resume the scan")
+		}
+		return trie.NewNode(), nil, nil
+	}
+
+	if !resume {
+		node := trie.NewNode()
+		return node, NewCheckpointer(path, interval, NewCheckpoint(shards, node, settings)), nil
+	}
+
+	checkpoint, err := LoadCheckpoint(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = checkpoint.Restore(shards, settings)
+	if err != nil {
+		return nil, nil, err
+	}
+	return checkpoint.Trie, NewCheckpointer(path, interval, checkpoint), nil
+}
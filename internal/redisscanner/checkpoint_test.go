@@ -0,0 +1,137 @@
+package redisscanner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-redis/redis"
+
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/trie"
+)
+
+var testSettings = CheckpointSettings{Pattern: "*", Tokenizer: "segment", Delimiters: ":"}
+
+// scanPages sends keys of the pages through metadata pool of the shard, the same way ScanRedisKeys does.
+func scanPages(shard *Shard, pages [][]string, cursors []uint64) []KeyRecord {
+	keyReceiver := make(chan KeyRecord)
+	go func() {
+		pool := newMetadataPool(shard, ScanOptions{}, serverFeatures{}, keyReceiver)
+		for index, page := range pages {
+			pool.Submit(page, cursors[index])
+		}
+		pool.Close()
+		close(keyReceiver)
+	}()
+
+	records := make([]KeyRecord, 0)
+	for record := range keyReceiver {
+		records = append(records, record)
+	}
+	return records
+}
+
+// resumeInMiddleOfPage interrupts the scan of the pages after inserting the given number of keys,
+// resumes it from the saved checkpoint and verifies that every key is inserted exactly once.
+func resumeInMiddleOfPage(t *testing.T, pages [][]string, cursors []uint64, interruptAfter int, resumePage int) {
+	directory, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	path := filepath.Join(directory, "scan.checkpoint")
+
+	// First run is interrupted in the middle of a page.
+	shard := &Shard{
+		Name:        "replica-1:6379",
+		PrimaryAddr: "primary:6379",
+		Client:      redis.NewClient(&redis.Options{Addr: "replica-1:6379"}),
+	}
+	node := trie.NewNode()
+	checkpoint := NewCheckpoint([]*Shard{shard}, node, testSettings)
+	for _, record := range scanPages(shard, pages, cursors)[:interruptAfter] {
+		node.Insert(record.Key)
+		checkpoint.Track(record)
+	}
+	if err := checkpoint.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Second run picks different replica of the same primary.
+	resumedShard := &Shard{
+		Name:        "replica-2:6379",
+		PrimaryAddr: "primary:6379",
+		Client:      redis.NewClient(&redis.Options{Addr: "replica-2:6379"}),
+	}
+	resumedCheckpoint, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resumedCheckpoint.Restore([]*Shard{resumedShard}, testSettings); err != nil {
+		t.Fatal(err)
+	}
+	if resumedShard.startCursor != 7 || resumedShard.skipKeys != 1 {
+		t.Fatalf(
+			"Incorrect restored position. Expected cursor %d skipping %d keys, got cursor %d skipping %d keys",
+			7,
+			1,
+			resumedShard.startCursor,
+			resumedShard.skipKeys,
+		)
+	}
+
+	// Scan resumes from the cursor returned with the page before the interrupted one.
+	resumedNode := resumedCheckpoint.Trie
+	for _, record := range scanPages(resumedShard, pages[resumePage:], cursors[resumePage:]) {
+		resumedNode.Insert(record.Key)
+		resumedCheckpoint.Track(record)
+	}
+
+	if count := resumedNode.Count(); count != 5 {
+		t.Errorf("Incorrect number of keys in resumed trie. Expected %d, got %d", 5, count)
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		edge := resumedNode.GetEdge(key)
+		if edge == nil || edge.PrefixCount != 1 {
+			t.Errorf("Key %q must be present exactly once in resumed trie", key)
+		}
+	}
+
+	shardCheckpoint := resumedCheckpoint.Shards[resumedShard.checkpointKey()]
+	if !shardCheckpoint.Completed || shardCheckpoint.KeysScanned != 5 || shardCheckpoint.PageKeys != 0 {
+		t.Errorf("Incorrect checkpoint after completing the scan: %+v", *shardCheckpoint)
+	}
+}
+
+func TestCheckpointResumeInMiddleOfPage(t *testing.T) {
+	// Interrupted after inserting "c", first key of the second page.
+	resumeInMiddleOfPage(t, [][]string{{"a", "b"}, {"c", "d", "e"}}, []uint64{7, 0}, 3, 1)
+}
+
+func TestCheckpointResumeAfterEmptyPage(t *testing.T) {
+	// Empty page doesn't move the checkpointed cursor, so resumed scan
+	// returns it again and "c" must be skipped from the page after it.
+	resumeInMiddleOfPage(t, [][]string{{"a", "b"}, {}, {"c", "d", "e"}}, []uint64{7, 9, 0}, 3, 1)
+}
+
+func TestCheckpointRestoreDifferentSettings(t *testing.T) {
+	shard := &Shard{Name: "primary:6379", Client: redis.NewClient(&redis.Options{Addr: "primary:6379"})}
+	checkpoint := NewCheckpoint([]*Shard{shard}, trie.NewNode(), testSettings)
+
+	settings := testSettings
+	settings.Pattern = "user:*"
+	if err := checkpoint.Restore([]*Shard{shard}, settings); err == nil {
+		t.Error("Restoring checkpoint with different pattern must fail")
+	}
+}
+
+func TestCheckpointRestoreUnknownShard(t *testing.T) {
+	shard := &Shard{Name: "primary:6379", Client: redis.NewClient(&redis.Options{Addr: "primary:6379"})}
+	checkpoint := NewCheckpoint([]*Shard{shard}, trie.NewNode(), testSettings)
+
+	otherShard := &Shard{Name: "other:6379", Client: redis.NewClient(&redis.Options{Addr: "other:6379"})}
+	if err := checkpoint.Restore([]*Shard{otherShard}, testSettings); err == nil {
+		t.Error("Restoring checkpoint of different primary must fail")
+	}
+}
@@ -0,0 +1,54 @@
+package redisscanner
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/go-redis/redis"
+)
+
+// GetClusterShards discovers all master shards of the redis cluster reachable via seed node in the URL.
+func GetClusterShards(connectionOptions ConnectionOptions) ([]*Shard, error) {
+	options, err := connectionOptions.redisOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	if options.DB != 0 {
+		return nil, errors.New("Redis cluster supports only database 0")
+	}
+
+	// Unless server name is given explicitly, every node is verified against its own address.
+	options.TLSConfig = connectionOptions.TLS.forNode(options.TLSConfig)
+
+	clusterClient := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs:       []string{options.Addr},
+		OnConnect:   options.OnConnect,
+		Password:    options.Password,
+		DialTimeout: options.DialTimeout,
+		ReadTimeout: options.ReadTimeout,
+		TLSConfig:   options.TLSConfig,
+	})
+	_, err = clusterClient.Ping().Result()
+	if err != nil {
+		return nil, describeConnectionError(options.Addr, options.TLSConfig != nil, err)
+	}
+
+	var mutex sync.Mutex
+	shards := make([]*Shard, 0)
+	err = clusterClient.ForEachMaster(func(client *redis.Client) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		shards = append(shards, &Shard{Name: client.Options().Addr, Client: client})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(shards, func(i int, j int) bool {
+		return shards[i].Name < shards[j].Name
+	})
+	return shards, nil
+}
@@ -0,0 +1,249 @@
+package redisscanner
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/Ashish-Bansal/redis-spectacles/internal/utils"
+)
+
+// Collector fills attributes of the scanned keys. Collectors are run on batches of records,
+// commands of all the collectors run on a batch are sent using single pipeline.
+type Collector interface {
+	// Queue adds commands looking up the attributes of the records into the pipeline and returns function
+	// which stores their replies into the records once the pipeline has been executed.
+	Queue(pipeline redis.Pipeliner, records []KeyRecord) func()
+}
+
+// newCollectors returns collectors requested by the scan options, except for typeCollector.
+func newCollectors(scanOptions ScanOptions, features serverFeatures) []Collector {
+	collectors := make([]Collector, 0)
+	if scanOptions.BigKeys {
+		collectors = append(collectors, sizeCollector{limit: scanOptions.TopKeys})
+	}
+	if scanOptions.TTL {
+		collectors = append(collectors, ttlCollector{})
+	}
+	if scanOptions.ObjectStats {
+		collectors = append(collectors, objectCollector{lfuPolicy: features.lfuPolicy})
+	}
+	if scanOptions.MemoryUsage {
+		collectors = append(collectors, memoryCollector{sampleRate: scanOptions.MemorySampleRate})
+	}
+	return append(collectors, scanOptions.Collectors...)
+}
+
+// runCollectors runs the collectors on the records of the shard using single pipeline. Transient failures
+// are retried the same way as SCAN, error is returned once retries are exhausted.
+// Failed commands leave the corresponding attributes unset.
+func runCollectors(shard *Shard, records []KeyRecord, collectors []Collector, maxRetries int) error {
+	if len(records) == 0 || len(collectors) == 0 {
+		return nil
+	}
+
+	retries := 0
+	for {
+		pipeline := shard.Client.Pipeline()
+		appliers := make([]func(), len(collectors))
+		for index, collector := range collectors {
+			appliers[index] = collector.Queue(pipeline, records)
+		}
+
+		startTime := time.Now()
+		_, err := pipeline.Exec()
+		shard.throttler.Observe(time.Since(startTime))
+		pipeline.Close()
+
+		if err != nil && isTransientError(err) {
+			if retries >= maxRetries {
+				return err
+			}
+			retries++
+			time.Sleep(time.Duration(retries) * retryBackoff)
+			continue
+		}
+
+		for _, apply := range appliers {
+			apply()
+		}
+		return nil
+	}
+}
+
+// recordBatch is a part of the SCAN page whose attributes are collected by a single worker.
+type recordBatch struct {
+	records []KeyRecord
+	err     error
+	done    chan struct{}
+}
+
+// metadataPool collects attributes of the keys of a shard using concurrent workers. Keys are sent
+// to the receiver page by page in the order the pages were scanned, so that checkpoint stays consistent.
+type metadataPool struct {
+	shard       *Shard
+	scanOptions ScanOptions
+
+	// typeCollector runs before other collectors, so that keys can be filtered by type and sized.
+	typeCollector Collector
+	collectors    []Collector
+
+	batches chan *recordBatch
+	pages   chan []*recordBatch
+	workers sync.WaitGroup
+	emitted chan struct{}
+
+	// err is set by emit once attributes of a page couldn't be collected, keys of that page
+	// and all the following pages aren't sent, so that checkpoint doesn't move past them.
+	mutex sync.Mutex
+	err   error
+}
+
+// newMetadataPool starts workers collecting attributes of the keys and sending them to the receiver.
+func newMetadataPool(
+	shard *Shard,
+	scanOptions ScanOptions,
+	features serverFeatures,
+	keyReceiver chan<- KeyRecord,
+) *metadataPool {
+	workers := utils.Max(scanOptions.MetadataWorkers, 1)
+	pool := &metadataPool{
+		shard:       shard,
+		scanOptions: scanOptions,
+		collectors:  newCollectors(scanOptions, features),
+		batches:     make(chan *recordBatch),
+		pages:       make(chan []*recordBatch, workers),
+		emitted:     make(chan struct{}),
+	}
+
+	if scanOptions.Type != "" && features.scanType {
+		pool.typeCollector = typeCollector{knownType: scanOptions.Type}
+	} else if scanOptions.Type != "" || scanOptions.KeyTypes || scanOptions.BigKeys {
+		pool.typeCollector = typeCollector{}
+	}
+
+	for worker := 0; worker < workers; worker++ {
+		pool.workers.Add(1)
+		go pool.work()
+	}
+	go pool.emit(keyReceiver)
+	return pool
+}
+
+// Submit queues keys of the SCAN page for collection of their attributes.
+func (pool *metadataPool) Submit(keys []string, nextCursor uint64) {
+	batchSize := pool.scanOptions.MetadataBatchSize
+	if batchSize <= 0 {
+		batchSize = len(keys)
+	}
+
+	page := make([]*recordBatch, 0)
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		batch := &recordBatch{records: make([]KeyRecord, 0, end-start), done: make(chan struct{})}
+		for _, key := range keys[start:end] {
+			batch.records = append(batch.records, KeyRecord{Key: key, Shard: pool.shard, Cursor: nextCursor})
+		}
+		page = append(page, batch)
+	}
+
+	pool.pages <- page
+	for _, batch := range page {
+		pool.batches <- batch
+	}
+}
+
+// Close waits until all the submitted keys have been sent to the receiver.
+// It returns the error due to which attributes of some keys couldn't be collected.
+func (pool *metadataPool) Close() error {
+	close(pool.batches)
+	close(pool.pages)
+	pool.workers.Wait()
+	<-pool.emitted
+	return pool.Err()
+}
+
+// Err returns the error due to which attributes of some keys couldn't be collected, nil if there's none.
+func (pool *metadataPool) Err() error {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return pool.err
+}
+
+func (pool *metadataPool) work() {
+	defer pool.workers.Done()
+	maxRetries := pool.scanOptions.MaxRetries
+	for batch := range pool.batches {
+		if pool.typeCollector != nil {
+			batch.err = runCollectors(pool.shard, batch.records, []Collector{pool.typeCollector}, maxRetries)
+			if batch.err != nil {
+				close(batch.done)
+				continue
+			}
+			batch.records = pool.filterByType(batch.records)
+		}
+		batch.err = runCollectors(pool.shard, batch.records, pool.collectors, maxRetries)
+		if pool.typeCollector != nil || len(pool.collectors) != 0 {
+			for index := range batch.records {
+				batch.records[index].hasStats = true
+			}
+		}
+		close(batch.done)
+	}
+}
+
+// filterByType drops keys of other types in case server couldn't filter them.
+func (pool *metadataPool) filterByType(records []KeyRecord) []KeyRecord {
+	if pool.scanOptions.Type == "" {
+		return records
+	}
+
+	filteredRecords := make([]KeyRecord, 0, len(records))
+	for _, record := range records {
+		if record.Type == pool.scanOptions.Type {
+			filteredRecords = append(filteredRecords, record)
+		}
+	}
+	return filteredRecords
+}
+
+// emit sends records of every page once all its batches are collected, marking the last one in the page.
+// Keys of the first non-empty page which were already scanned before the checkpoint was saved aren't sent again.
+func (pool *metadataPool) emit(keyReceiver chan<- KeyRecord) {
+	defer close(pool.emitted)
+	skipKeys := pool.shard.skipKeys
+	for page := range pool.pages {
+		records := make([]KeyRecord, 0)
+		for _, batch := range page {
+			<-batch.done
+			records = append(records, batch.records...)
+			if batch.err != nil && pool.Err() == nil {
+				pool.mutex.Lock()
+				pool.err = batch.err
+				pool.mutex.Unlock()
+			}
+		}
+		if pool.Err() != nil {
+			continue
+		}
+
+		if len(records) != 0 {
+			records[len(records)-1].LastInPage = true
+		}
+		// Empty pages don't move the checkpointed cursor, so keys are skipped from the first non-empty page.
+		if skipKeys > 0 && len(records) != 0 {
+			records = records[utils.Min64(skipKeys, int64(len(records))):]
+			skipKeys = 0
+		}
+		for _, record := range records {
+			keyReceiver <- record
+			atomic.AddInt64(&pool.shard.keysScanned, 1)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package redisscanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+func TestMetadataPoolStopsOnCollectorFailure(t *testing.T) {
+	shard := &Shard{
+		Name: "127.0.0.1:1",
+		Client: redis.NewClient(&redis.Options{
+			Addr:        "127.0.0.1:1",
+			DialTimeout: 100 * time.Millisecond,
+		}),
+	}
+
+	keyReceiver := make(chan KeyRecord)
+	errs := make(chan error, 1)
+	go func() {
+		pool := newMetadataPool(shard, ScanOptions{TTL: true}, serverFeatures{}, keyReceiver)
+		pool.Submit([]string{"a", "b"}, 7)
+		pool.Submit([]string{"c"}, 0)
+		errs <- pool.Close()
+		close(keyReceiver)
+	}()
+
+	received := 0
+	for range keyReceiver {
+		received++
+	}
+	if received != 0 {
+		t.Errorf("Keys whose attributes couldn't be collected must not be sent, got %d keys", received)
+	}
+	if err := <-errs; err == nil || !isTransientError(err) {
+		t.Errorf("Pool must report connection error of the pipeline, got %v", err)
+	}
+}
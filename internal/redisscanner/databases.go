@@ -0,0 +1,72 @@
+package redisscanner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseKeyspaceInfo returns indexes of the non-empty databases from output of INFO keyspace.
+func parseKeyspaceInfo(info string) []int {
+	databases := make([]int, 0)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "db") {
+			continue
+		}
+
+		separatorIndex := strings.Index(line, ":")
+		if separatorIndex == -1 {
+			continue
+		}
+
+		database, err := strconv.Atoi(line[len("db"):separatorIndex])
+		if err != nil {
+			continue
+		}
+
+		for _, field := range strings.Split(line[separatorIndex+1:], ",") {
+			if strings.HasPrefix(field, "keys=") && field != "keys=0" {
+				databases = append(databases, database)
+				break
+			}
+		}
+	}
+	sort.Ints(databases)
+	return databases
+}
+
+// splitIntoDatabases returns one shard per non-empty database of the node scanned by the given shard.
+// Keys of each database are put under db<index>/ branch, so that databases show up as top-level branches.
+func splitIntoDatabases(shard *Shard, connectionOptions ConnectionOptions) ([]*Shard, error) {
+	info, err := shard.Client.Info("keyspace").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	shards := make([]*Shard, 0)
+	for _, database := range parseKeyspaceInfo(info) {
+		databaseConnectionOptions := connectionOptions
+		databaseConnectionOptions.DB = database
+		options, err := databaseConnectionOptions.redisOptions()
+		if err != nil {
+			return nil, err
+		}
+		options.Addr = shard.Client.Options().Addr
+
+		client, err := GetRedisClient(options)
+		if err != nil {
+			return nil, err
+		}
+
+		shards = append(shards, &Shard{
+			Name:        fmt.Sprintf("%s/db%d", shard.Name, database),
+			PrimaryAddr: shard.PrimaryAddr,
+			Client:      client,
+			Branch:      fmt.Sprintf("db%d/", database),
+		})
+	}
+	shard.Client.Close()
+	return shards, nil
+}
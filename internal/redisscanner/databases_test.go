@@ -0,0 +1,23 @@
+package redisscanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeyspaceInfo(t *testing.T) {
+	info := "# Keyspace\r\n" +
+		"db10:keys=5,expires=0,avg_ttl=0\r\n" +
+		"db0:keys=1250,expires=12,avg_ttl=3600\r\n" +
+		"db2:keys=0,expires=0,avg_ttl=0\r\n" +
+		"dbx:keys=3,expires=0,avg_ttl=0\r\n"
+
+	expected := []int{0, 10}
+	if databases := parseKeyspaceInfo(info); !reflect.DeepEqual(expected, databases) {
+		t.Errorf("Incorrect non-empty databases. Expected %v, got %v", expected, databases)
+	}
+
+	if databases := parseKeyspaceInfo("# Keyspace\r\n"); len(databases) != 0 {
+		t.Errorf("Empty server must not have any databases, got %v", databases)
+	}
+}
@@ -0,0 +1,230 @@
+package redisscanner
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// serverFeatures describes how the server being scanned affects collection of the key attributes.
+type serverFeatures struct {
+	// scanType is whether server filters keys by the requested type, otherwise keys are filtered locally.
+	scanType bool
+	// lfuPolicy is whether server evicts keys using LFU policy, in which case OBJECT FREQ replaces OBJECT IDLETIME.
+	lfuPolicy bool
+}
+
+// detectServerFeatures looks up only the server features relevant to given scan options.
+func detectServerFeatures(client *redis.Client, scanOptions ScanOptions) serverFeatures {
+	features := serverFeatures{scanType: true}
+	if scanOptions.Type != "" {
+		features.scanType = supportsScanType(client)
+	}
+	if scanOptions.ObjectStats {
+		features.lfuPolicy = usesLFUPolicy(client)
+	}
+	return features
+}
+
+// supportsScanType returns whether server is new enough to accept TYPE argument of SCAN.
+func supportsScanType(client *redis.Client) bool {
+	info, err := client.Info("server").Result()
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "redis_version:") {
+			continue
+		}
+
+		version := strings.TrimPrefix(line, "redis_version:")
+		major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+		return err == nil && major >= 6
+	}
+	return false
+}
+
+// usesLFUPolicy returns whether server's maxmemory-policy is one of the LFU policies.
+func usesLFUPolicy(client *redis.Client) bool {
+	config, err := client.ConfigGet("maxmemory-policy").Result()
+	if err != nil || len(config) != 2 {
+		return false
+	}
+
+	policy, _ := config[1].(string)
+	return strings.Contains(policy, "lfu")
+}
+
+// typeCollector looks up type of the keys using TYPE. In case server has already filtered keys
+// by type, knownType is used instead without any lookup.
+type typeCollector struct {
+	knownType string
+}
+
+func (collector typeCollector) Queue(pipeline redis.Pipeliner, records []KeyRecord) func() {
+	if collector.knownType != "" {
+		return func() {
+			for index := range records {
+				records[index].Type = collector.knownType
+				records[index].Stats.SetType(collector.knownType)
+			}
+		}
+	}
+
+	commands := make([]*redis.StatusCmd, len(records))
+	for index, record := range records {
+		commands[index] = pipeline.Type(record.Key)
+	}
+
+	return func() {
+		for index, command := range commands {
+			keyType, err := command.Result()
+			if err != nil || keyType == "none" {
+				continue
+			}
+			records[index].Type = keyType
+			records[index].Stats.SetType(keyType)
+		}
+	}
+}
+
+// sizeCollector looks up size of the keys using STRLEN, HLEN, LLEN, SCARD, ZCARD or XLEN depending on
+// type of the key, so it must run after typeCollector. Keys of unknown type are left without size.
+// At most limit largest keys are kept for each prefix.
+type sizeCollector struct {
+	limit int
+}
+
+func (collector sizeCollector) Queue(pipeline redis.Pipeliner, records []KeyRecord) func() {
+	commands := make([]*redis.IntCmd, len(records))
+	for index, record := range records {
+		switch record.Type {
+		case "string":
+			commands[index] = pipeline.StrLen(record.Key)
+		case "hash":
+			commands[index] = pipeline.HLen(record.Key)
+		case "list":
+			commands[index] = pipeline.LLen(record.Key)
+		case "set":
+			commands[index] = pipeline.SCard(record.Key)
+		case "zset":
+			commands[index] = pipeline.ZCard(record.Key)
+		case "stream":
+			commands[index] = pipeline.XLen(record.Key)
+		}
+	}
+
+	return func() {
+		for index, command := range commands {
+			if command == nil {
+				continue
+			}
+
+			size, err := command.Result()
+			if err != nil {
+				continue
+			}
+			records[index].Stats.SetSize(records[index].PrefixedKey(), records[index].Type, size, collector.limit)
+		}
+	}
+}
+
+// ttlCollector looks up TTL of the keys using PTTL.
+type ttlCollector struct{}
+
+func (ttlCollector) Queue(pipeline redis.Pipeliner, records []KeyRecord) func() {
+	commands := make([]*redis.DurationCmd, len(records))
+	for index, record := range records {
+		commands[index] = pipeline.PTTL(record.Key)
+	}
+
+	return func() {
+		for index, command := range commands {
+			ttl, err := command.Result()
+			if err != nil {
+				continue
+			}
+
+			// PTTL replies with -1 for keys without expiry and -2 for missing keys.
+			switch ttl {
+			case -1 * time.Millisecond:
+				records[index].Stats.SetTTL(-1)
+			case -2 * time.Millisecond:
+			default:
+				records[index].Stats.SetTTL(ttl)
+			}
+		}
+	}
+}
+
+// objectCollector looks up encoding and idle time or access frequency of the keys using OBJECT.
+type objectCollector struct {
+	lfuPolicy bool
+}
+
+func (collector objectCollector) Queue(pipeline redis.Pipeliner, records []KeyRecord) func() {
+	encodingCommands := make([]*redis.StringCmd, len(records))
+	idleTimeCommands := make([]*redis.DurationCmd, len(records))
+	freqCommands := make([]*redis.IntCmd, len(records))
+	for index, record := range records {
+		encodingCommands[index] = pipeline.ObjectEncoding(record.Key)
+		if collector.lfuPolicy {
+			// OBJECT FREQ isn't supported by the client, so it's queued as a generic command.
+			freqCommands[index] = redis.NewIntCmd("object", "freq", record.Key)
+			pipeline.Process(freqCommands[index])
+		} else {
+			idleTimeCommands[index] = pipeline.ObjectIdleTime(record.Key)
+		}
+	}
+
+	return func() {
+		for index := range records {
+			stats := &records[index].Stats
+			if encoding, err := encodingCommands[index].Result(); err == nil {
+				stats.SetEncoding(encoding)
+			}
+
+			if collector.lfuPolicy {
+				if freq, err := freqCommands[index].Result(); err == nil {
+					stats.SetFreq(freq)
+				}
+			} else if idleTime, err := idleTimeCommands[index].Result(); err == nil {
+				stats.SetIdleTime(idleTime)
+			}
+		}
+	}
+}
+
+// memoryCollector measures memory usage of the fraction of keys given by sampleRate using MEMORY USAGE.
+type memoryCollector struct {
+	sampleRate float64
+}
+
+func (collector memoryCollector) Queue(pipeline redis.Pipeliner, records []KeyRecord) func() {
+	commands := make([]*redis.IntCmd, len(records))
+	for index, record := range records {
+		if collector.sampleRate >= 1 || rand.Float64() < collector.sampleRate {
+			commands[index] = pipeline.MemoryUsage(record.Key)
+		}
+	}
+
+	return func() {
+		for index, command := range commands {
+			if command == nil {
+				continue
+			}
+
+			bytes, err := command.Result()
+			if err != nil {
+				continue
+			}
+			records[index].Stats.MeasuredKeys = 1
+			records[index].Stats.Bytes = bytes
+		}
+	}
+}
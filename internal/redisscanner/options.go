@@ -0,0 +1,75 @@
+package redisscanner
+
+import "github.com/go-redis/redis"
+
+// redisOptions returns redis client options parsed from the URL and overridden by
+// explicitly passed DB, credentials, timeouts and TLS options.
+// Username of the URL is ignored, as servers older than redis 6 reject AUTH with username,
+// ACL user is authenticated only if username is passed explicitly.
+func (connectionOptions ConnectionOptions) redisOptions() (*redis.Options, error) {
+	options := &redis.Options{}
+	if connectionOptions.URL != "" {
+		var err error
+		options, err = redis.ParseURL(connectionOptions.URL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if connectionOptions.DB >= 0 {
+		options.DB = connectionOptions.DB
+	}
+	if connectionOptions.Password != "" {
+		options.Password = connectionOptions.Password
+	}
+	if connectionOptions.ConnectTimeout > 0 {
+		options.DialTimeout = connectionOptions.ConnectTimeout
+	}
+	if connectionOptions.ReadTimeout > 0 {
+		options.ReadTimeout = connectionOptions.ReadTimeout
+	}
+
+	tlsConfig, err := connectionOptions.TLS.apply(options.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+	options.TLSConfig = tlsConfig
+
+	if connectionOptions.Username != "" {
+		authenticateAsUser(options, connectionOptions.Username)
+	}
+	return options, nil
+}
+
+// authenticateAsUser makes client authenticate as given ACL user on every new connection.
+// Client would otherwise send AUTH with password only, which authenticates default user.
+func authenticateAsUser(options *redis.Options, username string) {
+	password := options.Password
+	db := options.DB
+	options.Password = ""
+	options.DB = 0
+	options.OnConnect = func(conn *redis.Conn) error {
+		err := conn.Process(redis.NewStatusCmd("auth", username, password))
+		if err != nil {
+			return err
+		}
+
+		if db > 0 {
+			return conn.Select(db).Err()
+		}
+		return nil
+	}
+}
+
+// withReadOnly makes client send READONLY on every new connection, which is required to read from cluster replicas.
+func withReadOnly(options *redis.Options) {
+	onConnect := options.OnConnect
+	options.OnConnect = func(conn *redis.Conn) error {
+		if onConnect != nil {
+			if err := onConnect(conn); err != nil {
+				return err
+			}
+		}
+		return conn.ReadOnly().Err()
+	}
+}
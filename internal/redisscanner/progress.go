@@ -0,0 +1,127 @@
+package redisscanner
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Progress describes how far the scan of the shards has got.
+type Progress struct {
+	KeysScanned int64
+
+	// TotalKeys is number of keys expected to be scanned, -1 if unknown e.g. because keys are filtered.
+	TotalKeys int64
+
+	KeysPerSecond float64
+
+	// Remaining is estimated time until the scan completes, -1 if unknown.
+	Remaining time.Duration
+}
+
+// ProgressTracker measures progress of the scan of the shards based on their DBSIZE.
+type ProgressTracker struct {
+	shards      []*Shard
+	scanOptions ScanOptions
+	startTime   time.Time
+
+	// startKeys is number of keys scanned before the scan started e.g. restored from checkpoint.
+	startKeys int64
+}
+
+// NewProgressTracker starts tracking progress of the scan, DBSIZE of the shards must have been fetched already.
+func NewProgressTracker(shards []*Shard, scanOptions ScanOptions) *ProgressTracker {
+	tracker := &ProgressTracker{shards: shards, scanOptions: scanOptions, startTime: time.Now()}
+	tracker.startKeys = tracker.keysScanned()
+	return tracker
+}
+
+func (tracker *ProgressTracker) keysScanned() int64 {
+	keysScanned := int64(0)
+	for _, shard := range tracker.shards {
+		keysScanned += shard.KeysScanned()
+	}
+	return keysScanned
+}
+
+// totalKeys returns number of keys which will be scanned once the scan completes, -1 if unknown.
+func (tracker *ProgressTracker) totalKeys() int64 {
+	if tracker.scanOptions.IsFiltered() {
+		return -1
+	}
+
+	totalKeys := int64(0)
+	for _, shard := range tracker.shards {
+		dbSize := shard.DBSize()
+		if dbSize < 0 {
+			return -1
+		}
+
+		if tracker.scanOptions.IsSampled() {
+			dbSize, _ = sampleTarget(shard, tracker.scanOptions)
+		}
+		totalKeys += dbSize
+	}
+	return totalKeys
+}
+
+// Progress returns current progress of the scan.
+func (tracker *ProgressTracker) Progress() Progress {
+	progress := Progress{
+		KeysScanned: tracker.keysScanned(),
+		TotalKeys:   tracker.totalKeys(),
+		Remaining:   -1,
+	}
+
+	elapsed := time.Since(tracker.startTime).Seconds()
+	if elapsed > 0 {
+		progress.KeysPerSecond = float64(progress.KeysScanned-tracker.startKeys) / elapsed
+	}
+
+	// Keys added since DBSIZE was fetched can make scanned keys exceed the total.
+	if progress.TotalKeys >= 0 && progress.KeysPerSecond > 0 {
+		remainingKeys := math.Max(float64(progress.TotalKeys-progress.KeysScanned), 0)
+		progress.Remaining = time.Duration(remainingKeys / progress.KeysPerSecond * float64(time.Second))
+	}
+	return progress
+}
+
+// Fraction returns completed fraction of the scan between 0 and 1, -1 if total is unknown.
+func (progress Progress) Fraction() float64 {
+	if progress.TotalKeys < 0 {
+		return -1
+	}
+	if progress.TotalKeys == 0 {
+		return 1
+	}
+	return math.Min(float64(progress.KeysScanned)/float64(progress.TotalKeys), 1)
+}
+
+// Describe returns human readable progress e.g. "Scanned 1200 / 5000 keys (24%), 3000 keys/s, ETA 1s".
+func (progress Progress) Describe() string {
+	message := fmt.Sprintf("Scanned %d", progress.KeysScanned)
+	if fraction := progress.Fraction(); fraction >= 0 {
+		message += fmt.Sprintf(" / %d keys (%.0f%%)", progress.TotalKeys, fraction*100)
+	} else {
+		message += " keys"
+	}
+
+	message += fmt.Sprintf(", %.0f keys/s", progress.KeysPerSecond)
+	if progress.Remaining >= 0 {
+		message += ", ETA " + progress.Remaining.Round(time.Second).String()
+	}
+	return message
+}
+
+// Bar returns progress bar of given width e.g. "[#####     ]", empty if total is unknown.
+func (progress Progress) Bar(width int) string {
+	fraction := progress.Fraction()
+	if fraction < 0 || width < 3 {
+		return ""
+	}
+
+	innerWidth := width - 2
+	filled := int(fraction * float64(innerWidth))
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(" ", innerWidth-filled) + "]"
+}
@@ -0,0 +1,115 @@
+package redisscanner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressDescription(t *testing.T) {
+	testCases := []struct {
+		progress    Progress
+		fraction    float64
+		description string
+		bar         string
+	}{
+		{
+			Progress{KeysScanned: 1200, TotalKeys: 5000, KeysPerSecond: 3000, Remaining: 1200 * time.Millisecond},
+			0.24,
+			"Scanned 1200 / 5000 keys (24%), 3000 keys/s, ETA 1s",
+			"[##        ]",
+		},
+		{
+			Progress{KeysScanned: 700, TotalKeys: -1, KeysPerSecond: 350.4, Remaining: -1},
+			-1,
+			"Scanned 700 keys, 350 keys/s",
+			"",
+		},
+		{
+			Progress{KeysScanned: 5200, TotalKeys: 5000, KeysPerSecond: 100, Remaining: 0},
+			1,
+			"Scanned 5200 / 5000 keys (100%), 100 keys/s, ETA 0s",
+			"[##########]",
+		},
+		{
+			Progress{KeysScanned: 0, TotalKeys: 0, Remaining: -1},
+			1,
+			"Scanned 0 / 0 keys (100%), 0 keys/s",
+			"[##########]",
+		},
+	}
+
+	for _, testCase := range testCases {
+		progress := testCase.progress
+		if fraction := progress.Fraction(); fraction != testCase.fraction {
+			t.Errorf("Incorrect fraction of %+v. Expected %v, got %v", progress, testCase.fraction, fraction)
+		}
+		if description := progress.Describe(); description != testCase.description {
+			t.Errorf("Incorrect description of %+v. Expected %q, got %q", progress, testCase.description, description)
+		}
+		if bar := progress.Bar(12); bar != testCase.bar {
+			t.Errorf("Incorrect bar of %+v. Expected %q, got %q", progress, testCase.bar, bar)
+		}
+	}
+
+	if bar := (Progress{KeysScanned: 1, TotalKeys: 2}).Bar(2); bar != "" {
+		t.Errorf("Bar narrower than its brackets must be empty, got %q", bar)
+	}
+}
+
+func progressShards(keysScanned []int64, dbSizes []int64) []*Shard {
+	shards := make([]*Shard, len(keysScanned))
+	for index := range shards {
+		shards[index] = &Shard{keysScanned: keysScanned[index], dbSize: dbSizes[index]}
+	}
+	return shards
+}
+
+func TestProgressTracker(t *testing.T) {
+	shards := progressShards([]int64{100, 0}, []int64{1000, 500})
+	tracker := NewProgressTracker(shards, ScanOptions{})
+	tracker.startTime = time.Now().Add(-10 * time.Second)
+	shards[0].keysScanned += 200
+	shards[1].keysScanned += 200
+
+	progress := tracker.Progress()
+	if progress.KeysScanned != 500 || progress.TotalKeys != 1500 {
+		t.Errorf("Incorrect progress. Expected %d / %d keys, got %d / %d keys", 500, 1500, progress.KeysScanned, progress.TotalKeys)
+	}
+
+	// Keys restored from checkpoint don't count towards the speed.
+	if progress.KeysPerSecond < 39 || progress.KeysPerSecond > 40 {
+		t.Errorf("Incorrect speed. Expected about %d keys/s, got %v", 40, progress.KeysPerSecond)
+	}
+	if progress.Remaining < 24*time.Second || progress.Remaining > 26*time.Second {
+		t.Errorf("Incorrect remaining time. Expected about %v, got %v", 25*time.Second, progress.Remaining)
+	}
+}
+
+func TestProgressTrackerTotalKeys(t *testing.T) {
+	testCases := []struct {
+		dbSizes     []int64
+		scanOptions ScanOptions
+		totalKeys   int64
+	}{
+		{[]int64{1000, 500}, ScanOptions{}, 1500},
+		{[]int64{1000, -1}, ScanOptions{}, -1},
+		{[]int64{1000, 500}, ScanOptions{Pattern: "user:*"}, -1},
+		{[]int64{1000, 500}, ScanOptions{Type: "hash"}, -1},
+		{[]int64{1000, 5}, ScanOptions{SampleFraction: 0.1}, 101},
+	}
+
+	for _, testCase := range testCases {
+		shards := progressShards(make([]int64, len(testCase.dbSizes)), testCase.dbSizes)
+		progress := NewProgressTracker(shards, testCase.scanOptions).Progress()
+		if progress.TotalKeys != testCase.totalKeys || progress.Remaining != -1 {
+			t.Errorf(
+				"Incorrect total of DBSIZE %v with options %+v. Expected %d keys without ETA, got %d keys with ETA %v",
+				testCase.dbSizes,
+				testCase.scanOptions,
+				testCase.totalKeys,
+				progress.TotalKeys,
+				progress.Remaining,
+			)
+		}
+	}
+}
@@ -1,28 +1,483 @@
 package redisscanner
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/go-redis/redis"
+
+	"github.com/Ashish-Bansal/redis-spectacles/internal/keystats"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/utils"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/iterator"
 )
 
-var rdb *redis.Client
+const retryBackoff = 500 * time.Millisecond
+
+// ConnectionOptions describes how to reach the redis deployment which needs to be scanned.
+type ConnectionOptions struct {
+	URL                  string
+	ClusterMode          bool
+	SentinelAddrs        []string
+	MasterName           string
+	PreferReplica        bool
+	AllowPrimaryFallback bool
+	AllDatabases         bool
+
+	// DB overrides database selected in the URL, unless it's negative.
+	DB             int
+	Username       string
+	Password       string
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	TLS            TLSOptions
+}
+
+// ScanOptions controls arguments of the SCAN command and how its failures are handled.
+type ScanOptions struct {
+	Pattern   string
+	BatchSize int64
+	Type      string
+
+	// SampleFraction is fraction of the keys which are sampled using SampleMethod, all keys are scanned if it's 0.
+	SampleFraction float64
+	SampleMethod   string
+
+	// MaxRetries is number of times failing SCAN call is retried from the last cursor.
+	MaxRetries int
 
-// GetRedisClient initialises and returns redis client from given redis URL
-func GetRedisClient(redisURL string) (*redis.Client, error) {
-	options, err := redis.ParseURL(redisURL)
+	// KeyTypes enables looking up type of every key.
+	KeyTypes bool
+
+	// TTL enables looking up TTL of every key.
+	TTL bool
+
+	// ObjectStats enables looking up encoding and idle time (or access frequency under LFU policy) of every key.
+	ObjectStats bool
+
+	// BigKeys enables looking up size of every key, so that TopKeys largest keys of each prefix are known.
+	BigKeys bool
+	TopKeys int
+
+	// MemoryUsage enables measuring memory usage of the fraction of keys given by MemorySampleRate.
+	MemoryUsage      bool
+	MemorySampleRate float64
+
+	// Collectors are run on every key in addition to the ones enabled by the options above.
+	Collectors []Collector
+
+	// MetadataBatchSize is number of keys whose attributes are looked up using single pipeline,
+	// MetadataWorkers is number of such pipelines run concurrently for each shard.
+	MetadataBatchSize int
+	MetadataWorkers   int
+
+	Throttle ThrottleOptions
+}
+
+// IsFiltered returns whether SCAN returns only subset of keys present in the database.
+func (scanOptions ScanOptions) IsFiltered() bool {
+	return scanOptions.Pattern != "" || scanOptions.Type != ""
+}
+
+// Shard represents single redis node whose keyspace is scanned independently.
+type Shard struct {
+	Name        string
+	PrimaryAddr string
+	Client      *redis.Client
+
+	// Branch is the top-level edge of the trie under which keys of the shard are inserted,
+	// keys are inserted directly under the root if it's empty.
+	Branch string
+
+	keysScanned int64
+	dbSize      int64
+	scanErr     error
+	throttler   *throttler
+
+	// startCursor, skipKeys and completed are restored from checkpoint while resuming the scan.
+	// skipKeys is number of keys of the page at startCursor which were already scanned.
+	startCursor uint64
+	skipKeys    int64
+	completed   bool
+}
+
+// KeyRecord represents a key scanned from the shard.
+type KeyRecord struct {
+	Key   string
+	Shard *Shard
+
+	// Type is type of the key, known only if it was looked up or used to filter the keys.
+	Type string
+
+	// Cursor is the SCAN cursor returned along with the page containing the key.
+	// Once the last key of the page is processed, scan can be resumed from it.
+	Cursor     uint64
+	LastInPage bool
+
+	// Stats holds attributes of the key collected in addition to the key name.
+	// hasStats is whether any collector ran on the key, Stats is empty otherwise.
+	Stats    keystats.KeyStats
+	hasStats bool
+}
+
+// Value returns stats of the key which are stored in the trie, nil if no collector ran on the key,
+// so that plain scans don't keep empty stats on every edge of the trie.
+func (record KeyRecord) Value() interface{} {
+	if !record.hasStats {
+		return nil
+	}
+	return record.Stats
+}
+
+// PrefixedKey returns key along with branch of its shard, which is how the key shows up in the trie.
+func (record KeyRecord) PrefixedKey() string {
+	return record.Shard.Branch + record.Key
+}
+
+// Tokenize returns item to be inserted into the trie for the key, tokenized under branch of its shard.
+func (record KeyRecord) Tokenize(tokenizer iterator.Tokenizer) interface{} {
+	item := tokenizer(record.Key)
+	if record.Shard.Branch == "" {
+		return item
+	}
+	return iterator.Branched{Branch: record.Shard.Branch, Item: item}
+}
+
+// Describe returns human readable description of the node which is being scanned for the shard.
+func (shard *Shard) Describe() string {
+	if shard.PrimaryAddr == "" {
+		return fmt.Sprintf("primary %s", shard.Name)
+	}
+	return fmt.Sprintf("replica %s of primary %s", shard.Name, shard.PrimaryAddr)
+}
+
+// KeysScanned returns number of keys scanned so far from the shard.
+func (shard *Shard) KeysScanned() int64 {
+	return atomic.LoadInt64(&shard.keysScanned)
+}
+
+// DBSize returns number of keys present in the shard when the scan started, -1 if unknown.
+func (shard *Shard) DBSize() int64 {
+	return atomic.LoadInt64(&shard.dbSize)
+}
+
+// ScanErr returns error due to which scan of the shard was aborted.
+// It must be called only after the scan has completed.
+func (shard *Shard) ScanErr() error {
+	return shard.scanErr
+}
+
+// MissedKeys returns estimate of keys which weren't scanned because of the scan error, -1 if unknown.
+func (shard *Shard) MissedKeys(scanOptions ScanOptions) int64 {
+	if shard.scanErr == nil {
+		return 0
+	}
+
+	dbSize := shard.DBSize()
+	if dbSize < 0 || scanOptions.IsFiltered() {
+		return -1
+	}
+	return utils.Max64(dbSize-shard.KeysScanned(), 0)
+}
+
+// GetRedisClient initialises redis client from given options and makes sure that it's reachable.
+// Returned error tells whether connection, TLS handshake, authentication or ping failed.
+func GetRedisClient(options *redis.Options) (*redis.Client, error) {
+	redisClient := redis.NewClient(options)
+	_, err := redisClient.Ping().Result()
+	if err != nil {
+		return redisClient, describeConnectionError(options.Addr, options.TLSConfig != nil, err)
+	}
+	return redisClient, nil
+}
+
+// GetShards returns list of shards which needs to be scanned for given connection options.
+// With replica preference, every shard is switched to one of the online replicas of its primary.
+// In all databases mode, every non-empty database is scanned as a separate shard.
+func GetShards(connectionOptions ConnectionOptions) ([]*Shard, error) {
+	if connectionOptions.AllDatabases && connectionOptions.ClusterMode {
+		return nil, errors.New("Redis cluster supports only database 0")
+	}
+
+	shards, err := getPrimaryShards(connectionOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	redisClient := redis.NewClient(options)
-	_, err = redisClient.Ping().Result()
-	return redisClient, err
+	if connectionOptions.PreferReplica {
+		for _, shard := range shards {
+			err := switchToReplica(
+				shard,
+				connectionOptions.ClusterMode,
+				connectionOptions.AllowPrimaryFallback,
+				connectionOptions.TLS,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !connectionOptions.AllDatabases {
+		return shards, nil
+	}
+
+	databaseShards := make([]*Shard, 0)
+	for _, shard := range shards {
+		splitShards, err := splitIntoDatabases(shard, connectionOptions)
+		if err != nil {
+			return nil, err
+		}
+		databaseShards = append(databaseShards, splitShards...)
+	}
+	return databaseShards, nil
 }
 
-// ScanRedisKeys scans redis database based on given pattern and sends them via channel.
-func ScanRedisKeys(redisClient *redis.Client, pattern string, batchSize int64, keyReceiver chan<- string) {
-	iterator := redisClient.Scan(0, pattern, batchSize).Iterator()
-	for iterator.Next() {
-		keyReceiver <- iterator.Val()
+func getPrimaryShards(connectionOptions ConnectionOptions) ([]*Shard, error) {
+	if len(connectionOptions.SentinelAddrs) != 0 {
+		if connectionOptions.ClusterMode {
+			return nil, errors.New("Cluster mode can't be used along with sentinel")
+		}
+
+		shard, err := GetSentinelShard(connectionOptions)
+		if err != nil {
+			return nil, err
+		}
+		return []*Shard{shard}, nil
+	}
+
+	if connectionOptions.URL == "" {
+		return nil, errors.New("Either redis URL or sentinel addresses must be provided")
+	}
+
+	if connectionOptions.ClusterMode {
+		return GetClusterShards(connectionOptions)
+	}
+
+	options, err := connectionOptions.redisOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetRedisClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return []*Shard{{Name: client.Options().Addr, Client: client}}, nil
+}
+
+func scanPage(redisClient *redis.Client, cursor uint64, scanOptions ScanOptions) *redis.ScanCmd {
+	args := []interface{}{"scan", cursor}
+	if scanOptions.Pattern != "" {
+		args = append(args, "match", scanOptions.Pattern)
+	}
+	if scanOptions.BatchSize > 0 {
+		args = append(args, "count", scanOptions.BatchSize)
+	}
+	if scanOptions.Type != "" {
+		args = append(args, "type", scanOptions.Type)
+	}
+
+	cmd := redis.NewScanCmd(redisClient.Process, args...)
+	redisClient.Process(cmd)
+	return cmd
+}
+
+// isTransientError returns whether command might succeed if it's retried.
+func isTransientError(err error) bool {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+
+	for _, prefix := range []string{"LOADING", "BUSY", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN"} {
+		if strings.HasPrefix(err.Error(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInterruption returns whether error is caused by cancellation or deadline of the scan context.
+func isInterruption(err error) bool {
+	return err == context.Canceled || err == context.DeadlineExceeded
+}
+
+// ScanRedisKeys scans the shard based on given scan options and sends keys via channel, along with
+// the attributes filled by the collectors. Transient failures are retried from the last cursor,
+// error is returned once retries are exhausted. Keys of the page whose attributes couldn't be collected
+// aren't sent, scan stops with that error instead.
+// Once the context is done, scan stops with context error after sending the keys of the current page,
+// so that receiver always gets complete pages. Receiver must keep reading until channel is closed.
+// In sampling mode, scan stops once the sampled fraction of DBSIZE keys has been sent.
+func ScanRedisKeys(
+	ctx context.Context,
+	shard *Shard,
+	scanOptions ScanOptions,
+	keyReceiver chan<- KeyRecord,
+) (err error) {
+	if shard.completed {
+		return nil
+	}
+
+	// SCAN supports TYPE argument since redis 6, keys are filtered locally on older servers.
+	features := detectServerFeatures(shard.Client, scanOptions)
+	pageScanOptions := scanOptions
+	if !features.scanType {
+		pageScanOptions.Type = ""
+	}
+
+	target := int64(-1)
+	if scanOptions.IsSampled() {
+		if target, err = sampleTarget(shard, scanOptions); err != nil {
+			return err
+		}
+	}
+	randomSampling := scanOptions.IsSampled() && scanOptions.SampleMethod == SampleMethodRandomKey
+
+	pool := newMetadataPool(shard, scanOptions, features, keyReceiver)
+	defer func() {
+		// Keys whose attributes couldn't be collected are missed, so it takes precedence over other errors.
+		if poolErr := pool.Close(); poolErr != nil {
+			err = poolErr
+		}
+	}()
+
+	cursor := shard.startCursor
+	retries := 0
+	sampled := int64(0)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := pool.Err(); err != nil {
+			return err
+		}
+
+		if target >= 0 && sampled >= target {
+			return nil
+		}
+
+		if err := shard.throttler.Wait(ctx); err != nil {
+			return err
+		}
+
+		var keys []string
+		var nextCursor uint64
+		var err error
+		startTime := time.Now()
+		if randomSampling {
+			keys, err = randomKeys(shard.Client, utils.Min64(utils.Max64(scanOptions.BatchSize, 1), target-sampled))
+		} else {
+			keys, nextCursor, err = scanPage(shard.Client, cursor, pageScanOptions).Result()
+		}
+		shard.throttler.Observe(time.Since(startTime))
+		if err != nil {
+			if !isTransientError(err) || retries >= scanOptions.MaxRetries {
+				return err
+			}
+
+			retries++
+			select {
+			case <-time.After(time.Duration(retries) * retryBackoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+		retries = 0
+
+		if target >= 0 {
+			keys = truncateSample(keys, target-sampled)
+		}
+
+		pool.Submit(keys, nextCursor)
+		sampled += int64(len(keys))
+
+		if (randomSampling && len(keys) == 0) || (!randomSampling && nextCursor == 0) {
+			return nil
+		}
+		cursor = nextCursor
+	}
+}
+
+// FetchDBSizes looks up number of keys present in each of the shards in parallel,
+// it must be called before the shards are scanned.
+func FetchDBSizes(shards []*Shard) {
+	var waitGroup sync.WaitGroup
+	for _, shard := range shards {
+		waitGroup.Add(1)
+		go func(shard *Shard) {
+			defer waitGroup.Done()
+
+			dbSize, err := shard.Client.DBSize().Result()
+			if err != nil {
+				dbSize = -1
+			}
+			atomic.StoreInt64(&shard.dbSize, dbSize)
+		}(shard)
+	}
+	waitGroup.Wait()
+}
+
+// ScanShards starts scanning all the shards in parallel in background and sends merged stream of keys via channel.
+// Channel is closed once every shard has been scanned or the context is done,
+// errors are reported via ScanErr of the shards. DBSIZE of the shards must have been fetched already.
+// Throttlers are set up before it returns, so that throttling can be described while the scan runs.
+// Shards of the same node (e.g. its databases) share the throttler, so the limits apply per node.
+func ScanShards(ctx context.Context, shards []*Shard, scanOptions ScanOptions, keyReceiver chan<- KeyRecord) {
+	throttlers := make(map[string]*throttler)
+	for _, shard := range shards {
+		addr := shard.Client.Options().Addr
+		if _, ok := throttlers[addr]; !ok {
+			throttlers[addr] = newThrottler(shard.Client, scanOptions.Throttle)
+		}
+		shard.throttler = throttlers[addr]
+	}
+
+	var waitGroup sync.WaitGroup
+	for _, shard := range shards {
+		waitGroup.Add(1)
+		go func(shard *Shard) {
+			defer waitGroup.Done()
+			shard.scanErr = ScanRedisKeys(ctx, shard, scanOptions, keyReceiver)
+		}(shard)
+	}
+	go func() {
+		waitGroup.Wait()
+		close(keyReceiver)
+	}()
+}
+
+// DescribeScanErrors returns one message per shard whose scan failed.
+// Shards whose scan was interrupted via context aren't reported.
+func DescribeScanErrors(shards []*Shard, scanOptions ScanOptions) []string {
+	messages := make([]string, 0)
+	for _, shard := range shards {
+		err := shard.ScanErr()
+		if err == nil || isInterruption(err) {
+			continue
+		}
+
+		missed := "unknown number of"
+		if missedKeys := shard.MissedKeys(scanOptions); missedKeys >= 0 {
+			missed = fmt.Sprintf("about %d", missedKeys)
+		}
+		messages = append(messages, fmt.Sprintf(
+			"Scan of %s is incomplete, %s keys missed: %v",
+			shard.Describe(),
+			missed,
+			err,
+		))
 	}
-	close(keyReceiver)
+	return messages
 }
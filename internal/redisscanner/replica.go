@@ -0,0 +1,104 @@
+package redisscanner
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis"
+)
+
+type replicaInfo struct {
+	Addr  string
+	State string
+	Lag   int
+}
+
+// parseReplicationInfo extracts connected replicas from output of INFO replication.
+func parseReplicationInfo(info string) []replicaInfo {
+	replicas := make([]replicaInfo, 0)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "slave") {
+			continue
+		}
+
+		separatorIndex := strings.Index(line, ":")
+		if separatorIndex == -1 {
+			continue
+		}
+		if _, err := strconv.Atoi(line[len("slave"):separatorIndex]); err != nil {
+			continue
+		}
+
+		fields := make(map[string]string)
+		for _, field := range strings.Split(line[separatorIndex+1:], ",") {
+			keyValue := strings.SplitN(field, "=", 2)
+			if len(keyValue) == 2 {
+				fields[keyValue[0]] = keyValue[1]
+			}
+		}
+
+		lag, _ := strconv.Atoi(fields["lag"])
+		replicas = append(replicas, replicaInfo{
+			Addr:  net.JoinHostPort(fields["ip"], fields["port"]),
+			State: fields["state"],
+			Lag:   lag,
+		})
+	}
+	return replicas
+}
+
+// switchToReplica points shard to the least lagging online replica of its primary.
+// In case no replica is reachable, shard keeps using primary only if fallback is allowed.
+// Replicas are verified against their own addresses unless TLS server name is given explicitly.
+func switchToReplica(shard *Shard, readOnly bool, allowPrimaryFallback bool, tlsOptions TLSOptions) error {
+	info, err := shard.Client.Info("replication").Result()
+	if err != nil {
+		return err
+	}
+
+	replicas := parseReplicationInfo(info)
+	var chosenClient *redis.Client
+	var chosenReplica replicaInfo
+	for _, replica := range replicas {
+		if replica.State != "online" {
+			continue
+		}
+		if chosenClient != nil && replica.Lag >= chosenReplica.Lag {
+			continue
+		}
+
+		options := *shard.Client.Options()
+		options.Addr = replica.Addr
+		options.TLSConfig = tlsOptions.forNode(options.TLSConfig)
+		if readOnly {
+			withReadOnly(&options)
+		}
+
+		client, err := GetRedisClient(&options)
+		if err != nil {
+			client.Close()
+			continue
+		}
+
+		if chosenClient != nil {
+			chosenClient.Close()
+		}
+		chosenClient = client
+		chosenReplica = replica
+	}
+
+	if chosenClient == nil {
+		if allowPrimaryFallback {
+			return nil
+		}
+		return fmt.Errorf("No online replica found for primary %s", shard.Name)
+	}
+
+	shard.PrimaryAddr = shard.Name
+	shard.Name = chosenReplica.Addr
+	shard.Client = chosenClient
+	return nil
+}
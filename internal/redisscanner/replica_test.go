@@ -0,0 +1,28 @@
+package redisscanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReplicationInfo(t *testing.T) {
+	info := "# Replication\r\n" +
+		"role:master\r\n" +
+		"connected_slaves:2\r\n" +
+		"slave0:ip=10.0.0.2,port=6379,state=online,offset=1200,lag=1\r\n" +
+		"slave1:ip=10.0.0.3,port=6380,state=wait_bgsave,offset=0,lag=0\r\n" +
+		"slave_read_only:1\r\n" +
+		"master_repl_offset:1200\r\n"
+
+	expected := []replicaInfo{
+		{Addr: "10.0.0.2:6379", State: "online", Lag: 1},
+		{Addr: "10.0.0.3:6380", State: "wait_bgsave", Lag: 0},
+	}
+	if replicas := parseReplicationInfo(info); !reflect.DeepEqual(expected, replicas) {
+		t.Errorf("Incorrect replicas. Expected %+v, got %+v", expected, replicas)
+	}
+
+	if replicas := parseReplicationInfo("# Replication\r\nrole:slave\r\nslave_read_only:1\r\n"); len(replicas) != 0 {
+		t.Errorf("Replica must not have any replicas, got %+v", replicas)
+	}
+}
@@ -0,0 +1,153 @@
+package redisscanner
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-redis/redis"
+
+	"github.com/Ashish-Bansal/redis-spectacles/internal/utils"
+)
+
+// Sampling methods, partial SCAN visits keys in order of hash table buckets while RANDOMKEY samples
+// keys independently, possibly returning same key more than once.
+const (
+	SampleMethodScan      = "scan"
+	SampleMethodRandomKey = "randomkey"
+)
+
+// confidenceZ is z-score of the 95% confidence interval of the estimates.
+const confidenceZ = 1.96
+
+// IsSampled returns whether only a fraction of keys is scanned.
+func (scanOptions ScanOptions) IsSampled() bool {
+	return scanOptions.SampleFraction > 0
+}
+
+// Validate returns error in case scan options can't be used together.
+func (scanOptions ScanOptions) Validate() error {
+	if scanOptions.BigKeys && scanOptions.TopKeys < 1 {
+		return errors.New("Number of largest keys shown for each prefix must be at least 1")
+	}
+	if !scanOptions.IsSampled() {
+		return nil
+	}
+
+	if scanOptions.SampleFraction > 1 {
+		return errors.New("Sample fraction must be between 0 and 1")
+	}
+	if scanOptions.SampleMethod != SampleMethodScan && scanOptions.SampleMethod != SampleMethodRandomKey {
+		return fmt.Errorf("Unknown sample method %q", scanOptions.SampleMethod)
+	}
+	// Filtered keys can't be scaled to DBSIZE, since it's unknown how many keys match the filter.
+	if scanOptions.IsFiltered() {
+		return errors.New("Sampling can't be used along with scan pattern or type")
+	}
+	return nil
+}
+
+// sampleTarget returns number of keys which need to be sampled from the shard.
+func sampleTarget(shard *Shard, scanOptions ScanOptions) (int64, error) {
+	dbSize := shard.DBSize()
+	if dbSize < 0 {
+		return 0, errors.New("Unable to sample keys, DBSIZE of the shard is unknown")
+	}
+	return int64(math.Ceil(float64(dbSize) * scanOptions.SampleFraction)), nil
+}
+
+// truncateSample drops keys of the page beyond the remaining number of keys to be sampled.
+// COUNT is only a hint, so page can hold more keys than needed to complete the sample.
+func truncateSample(keys []string, remaining int64) []string {
+	if int64(len(keys)) > remaining {
+		return keys[:utils.Max64(remaining, 0)]
+	}
+	return keys
+}
+
+// randomKeys returns given number of random keys using pipelined RANDOMKEY. It returns no keys if database is empty.
+func randomKeys(client *redis.Client, count int64) ([]string, error) {
+	pipeline := client.Pipeline()
+	defer pipeline.Close()
+
+	commands := make([]*redis.StringCmd, count)
+	for index := range commands {
+		commands[index] = pipeline.RandomKey()
+	}
+	if _, err := pipeline.Exec(); err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	keys := make([]string, len(commands))
+	for index, command := range commands {
+		keys[index] = command.Val()
+	}
+	return keys, nil
+}
+
+// Estimator scales counts of the sampled keys to the whole keyspace. Nil estimator is used when every key
+// was scanned, in which case counts are exact.
+type Estimator struct {
+	// Population is number of keys in the scanned databases, SampleSize is number of keys sampled out of them.
+	Population int64
+	SampleSize int64
+}
+
+// NewEstimator returns estimator for the keys sampled from the shards, nil if the keys weren't sampled.
+func NewEstimator(shards []*Shard, scanOptions ScanOptions) *Estimator {
+	if !scanOptions.IsSampled() {
+		return nil
+	}
+
+	estimator := &Estimator{}
+	for _, shard := range shards {
+		estimator.Population += utils.Max64(shard.DBSize(), 0)
+		estimator.SampleSize += shard.KeysScanned()
+	}
+	return estimator
+}
+
+// Estimate returns estimated number of keys in the keyspace given number of sampled keys,
+// along with margin of error of the 95% confidence interval.
+func (estimator *Estimator) Estimate(count int) (int64, int64) {
+	if estimator == nil {
+		return int64(count), 0
+	}
+	if estimator.SampleSize == 0 {
+		return 0, 0
+	}
+
+	proportion := float64(count) / float64(estimator.SampleSize)
+	standardError := math.Sqrt(proportion * (1 - proportion) / float64(estimator.SampleSize))
+	population := float64(estimator.Population)
+	return int64(math.Round(proportion * population)), int64(math.Round(confidenceZ * standardError * population))
+}
+
+// EstimatedCount returns estimated number of keys in the keyspace given number of sampled keys.
+func (estimator *Estimator) EstimatedCount(count int) int {
+	estimate, _ := estimator.Estimate(count)
+	return int(estimate)
+}
+
+// Describe returns estimated number of keys along with margin of error e.g. "~1200 ±80",
+// exact count in case keys weren't sampled.
+func (estimator *Estimator) Describe(count int) string {
+	if estimator == nil {
+		return fmt.Sprintf("%d", count)
+	}
+
+	estimate, margin := estimator.Estimate(count)
+	return fmt.Sprintf("~%d ±%d", estimate, margin)
+}
+
+// DescribeSample returns human readable description of the sample the estimates are based upon.
+func (estimator *Estimator) DescribeSample() string {
+	return fmt.Sprintf(
+		"Estimated from a sample of %d out of %d keys, ± gives 95%% confidence interval",
+		estimator.SampleSize,
+		estimator.Population,
+	)
+}
@@ -0,0 +1,134 @@
+package redisscanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		scanOptions ScanOptions
+		valid       bool
+	}{
+		{"full scan", ScanOptions{}, true},
+		{"scan sample", ScanOptions{SampleFraction: 0.1, SampleMethod: SampleMethodScan}, true},
+		{"randomkey sample", ScanOptions{SampleFraction: 1, SampleMethod: SampleMethodRandomKey}, true},
+		{"fraction above one", ScanOptions{SampleFraction: 1.5, SampleMethod: SampleMethodScan}, false},
+		{"unknown method", ScanOptions{SampleFraction: 0.1, SampleMethod: "reservoir"}, false},
+		{"sample with pattern", ScanOptions{SampleFraction: 0.1, SampleMethod: SampleMethodScan, Pattern: "user:*"}, false},
+		{"sample with type", ScanOptions{SampleFraction: 0.1, SampleMethod: SampleMethodScan, Type: "hash"}, false},
+		{"big keys", ScanOptions{BigKeys: true, TopKeys: 1}, true},
+		{"no big keys", ScanOptions{BigKeys: true, TopKeys: 0}, false},
+		{"negative big keys", ScanOptions{BigKeys: true, TopKeys: -1}, false},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.scanOptions.Validate()
+		if (err == nil) != testCase.valid {
+			t.Errorf("Incorrect validation of %s. Expected valid %t, got error %v", testCase.name, testCase.valid, err)
+		}
+	}
+}
+
+func TestSampleTarget(t *testing.T) {
+	testCases := []struct {
+		dbSize   int64
+		fraction float64
+		expected int64
+	}{
+		{1000, 0.1, 100},
+		{1001, 0.1, 101},
+		{5, 0.01, 1},
+		{0, 0.5, 0},
+		{10, 1, 10},
+	}
+
+	for _, testCase := range testCases {
+		shard := &Shard{dbSize: testCase.dbSize}
+		target, err := sampleTarget(shard, ScanOptions{SampleFraction: testCase.fraction})
+		if err != nil || target != testCase.expected {
+			t.Errorf(
+				"Incorrect sample target of %v out of %d keys. Expected %d, got %d (error %v)",
+				testCase.fraction,
+				testCase.dbSize,
+				testCase.expected,
+				target,
+				err,
+			)
+		}
+	}
+
+	if _, err := sampleTarget(&Shard{dbSize: -1}, ScanOptions{SampleFraction: 0.1}); err == nil {
+		t.Error("Sample target must not be known when DBSIZE is unknown")
+	}
+}
+
+func TestTruncateSample(t *testing.T) {
+	testCases := []struct {
+		keys      []string
+		remaining int64
+		expected  []string
+	}{
+		{[]string{"a", "b", "c"}, 5, []string{"a", "b", "c"}},
+		{[]string{"a", "b", "c"}, 3, []string{"a", "b", "c"}},
+		{[]string{"a", "b", "c"}, 2, []string{"a", "b"}},
+		{[]string{"a", "b", "c"}, 0, []string{}},
+		{[]string{}, 2, []string{}},
+	}
+
+	for _, testCase := range testCases {
+		actual := truncateSample(testCase.keys, testCase.remaining)
+		if !reflect.DeepEqual(testCase.expected, actual) {
+			t.Errorf(
+				"Incorrect sampled keys with %d remaining. Expected %q, got %q",
+				testCase.remaining,
+				testCase.expected,
+				actual,
+			)
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	testCases := []struct {
+		estimator *Estimator
+		count     int
+		estimate  int64
+		margin    int64
+	}{
+		{nil, 42, 42, 0},
+		{&Estimator{Population: 1000, SampleSize: 0}, 0, 0, 0},
+		{&Estimator{Population: 1000, SampleSize: 100}, 25, 250, 85},
+		{&Estimator{Population: 1000, SampleSize: 100}, 100, 1000, 0},
+		{&Estimator{Population: 1000, SampleSize: 100}, 0, 0, 0},
+		{&Estimator{Population: 1000, SampleSize: 1000}, 500, 500, 31},
+	}
+
+	for _, testCase := range testCases {
+		estimate, margin := testCase.estimator.Estimate(testCase.count)
+		if estimate != testCase.estimate || margin != testCase.margin {
+			t.Errorf(
+				"Incorrect estimate of %d keys using %+v. Expected %d ±%d, got %d ±%d",
+				testCase.count,
+				testCase.estimator,
+				testCase.estimate,
+				testCase.margin,
+				estimate,
+				margin,
+			)
+		}
+	}
+}
+
+func TestDescribeEstimate(t *testing.T) {
+	var exact *Estimator
+	if description := exact.Describe(42); description != "42" {
+		t.Errorf("Incorrect description of exact count. Expected %q, got %q", "42", description)
+	}
+
+	estimator := &Estimator{Population: 1000, SampleSize: 100}
+	if description := estimator.Describe(25); description != "~250 ±85" {
+		t.Errorf("Incorrect description of estimate. Expected %q, got %q", "~250 ±85", description)
+	}
+}
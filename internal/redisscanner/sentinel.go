@@ -0,0 +1,59 @@
+package redisscanner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/go-redis/redis"
+)
+
+// GetSentinelShard asks sentinels for the current address of the master and returns shard connected to it.
+// Credentials and DB are picked from redis URL and the other connection options.
+func GetSentinelShard(connectionOptions ConnectionOptions) (*Shard, error) {
+	if connectionOptions.MasterName == "" {
+		return nil, errors.New("Master name is required to discover redis via sentinel")
+	}
+
+	options, err := connectionOptions.redisOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Host of the URL isn't used, so sentinels and master are verified against their own addresses.
+	options.TLSConfig = connectionOptions.TLS.forNode(options.TLSConfig)
+
+	var lastErr error
+	for _, sentinelAddr := range connectionOptions.SentinelAddrs {
+		sentinel := redis.NewSentinelClient(&redis.Options{
+			Addr:        sentinelAddr,
+			DialTimeout: options.DialTimeout,
+			ReadTimeout: options.ReadTimeout,
+			TLSConfig:   options.TLSConfig,
+		})
+
+		addr, err := getMasterAddr(sentinel, connectionOptions.MasterName)
+		sentinel.Close()
+
+		if err != nil {
+			lastErr = fmt.Errorf("sentinel %s: %v", sentinelAddr, err)
+			continue
+		}
+
+		options.Addr = addr
+		client, err := GetRedisClient(options)
+		if err != nil {
+			return nil, err
+		}
+		return &Shard{Name: addr, Client: client}, nil
+	}
+	return nil, lastErr
+}
+
+func getMasterAddr(sentinel *redis.SentinelClient, masterName string) (string, error) {
+	masterAddr, err := sentinel.GetMasterAddrByName(masterName).Result()
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(masterAddr[0], masterAddr[1]), nil
+}
@@ -0,0 +1,206 @@
+package redisscanner
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const minAdaptiveDelay = 10 * time.Millisecond
+const maxAdaptiveDelay = 5 * time.Second
+const serverLoadCheckInterval = time.Second
+
+// ThrottleOptions controls how fast SCAN calls are sent to a redis node.
+type ThrottleOptions struct {
+	// MaxOpsPerSecond limits number of SCAN calls per second, 0 means no limit.
+	MaxOpsPerSecond float64
+
+	// Adaptive makes scanner back off when SCAN round trip time exceeds LatencyThreshold
+	// or instantaneous_ops_per_sec of the server exceeds ServerOpsThreshold (if non-zero).
+	Adaptive           bool
+	LatencyThreshold   time.Duration
+	ServerOpsThreshold int64
+}
+
+// throttler paces SCAN calls sent to single redis node, it's shared by all the shards scanned on the node.
+type throttler struct {
+	client  *redis.Client
+	options ThrottleOptions
+
+	mutex           sync.Mutex
+	lastCall        time.Time
+	lastLoadCheck   time.Time
+	adaptiveDelay   time.Duration
+	backoffReason   string
+	serverOverloads bool
+}
+
+func newThrottler(client *redis.Client, options ThrottleOptions) *throttler {
+	return &throttler{client: client, options: options}
+}
+
+// Wait blocks till the next SCAN call is allowed to be sent.
+func (throttler *throttler) Wait(ctx context.Context) error {
+	if throttler == nil {
+		return nil
+	}
+
+	throttler.checkServerLoad()
+
+	throttler.mutex.Lock()
+	delay := throttler.adaptiveDelay
+	if throttler.options.MaxOpsPerSecond > 0 {
+		interval := time.Duration(float64(time.Second) / throttler.options.MaxOpsPerSecond)
+		if interval > delay {
+			delay = interval
+		}
+	}
+	wait := time.Until(throttler.lastCall.Add(delay))
+	throttler.mutex.Unlock()
+
+	if wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	throttler.mutex.Lock()
+	throttler.lastCall = time.Now()
+	throttler.mutex.Unlock()
+	return nil
+}
+
+// Observe adapts the delay between SCAN calls based on round trip time of the last call.
+func (throttler *throttler) Observe(roundTripTime time.Duration) {
+	if throttler == nil || !throttler.options.Adaptive {
+		return
+	}
+
+	throttler.mutex.Lock()
+	defer throttler.mutex.Unlock()
+
+	if roundTripTime > throttler.options.LatencyThreshold {
+		throttler.backOff(fmt.Sprintf("SCAN latency %v", roundTripTime.Round(time.Millisecond)))
+		return
+	}
+
+	if !throttler.serverOverloads {
+		throttler.recover()
+	}
+}
+
+// checkServerLoad backs off in case server is busier than the configured threshold.
+// INFO is sent at most once per serverLoadCheckInterval.
+func (throttler *throttler) checkServerLoad() {
+	if !throttler.options.Adaptive || throttler.options.ServerOpsThreshold <= 0 {
+		return
+	}
+
+	throttler.mutex.Lock()
+	if time.Since(throttler.lastLoadCheck) < serverLoadCheckInterval {
+		throttler.mutex.Unlock()
+		return
+	}
+	throttler.lastLoadCheck = time.Now()
+	throttler.mutex.Unlock()
+
+	info, err := throttler.client.Info("stats").Result()
+	if err != nil {
+		return
+	}
+	opsPerSecond, ok := parseInfoField(info, "instantaneous_ops_per_sec")
+	if !ok {
+		return
+	}
+
+	throttler.mutex.Lock()
+	defer throttler.mutex.Unlock()
+
+	throttler.serverOverloads = opsPerSecond > throttler.options.ServerOpsThreshold
+	if throttler.serverOverloads {
+		throttler.backOff(fmt.Sprintf("server at %d ops/s", opsPerSecond))
+	}
+}
+
+func (throttler *throttler) backOff(reason string) {
+	throttler.adaptiveDelay *= 2
+	if throttler.adaptiveDelay < minAdaptiveDelay {
+		throttler.adaptiveDelay = minAdaptiveDelay
+	}
+	if throttler.adaptiveDelay > maxAdaptiveDelay {
+		throttler.adaptiveDelay = maxAdaptiveDelay
+	}
+	throttler.backoffReason = reason
+}
+
+func (throttler *throttler) recover() {
+	throttler.adaptiveDelay /= 2
+	if throttler.adaptiveDelay < minAdaptiveDelay {
+		throttler.adaptiveDelay = 0
+		throttler.backoffReason = ""
+	}
+}
+
+// State returns human readable description of the current throttling, empty if scan isn't throttled.
+func (throttler *throttler) State() string {
+	if throttler == nil {
+		return ""
+	}
+
+	throttler.mutex.Lock()
+	defer throttler.mutex.Unlock()
+
+	if throttler.adaptiveDelay > 0 {
+		return fmt.Sprintf("backing off %v per SCAN (%s)", throttler.adaptiveDelay, throttler.backoffReason)
+	}
+	if throttler.options.MaxOpsPerSecond > 0 {
+		return fmt.Sprintf("limited to %g SCAN/s", throttler.options.MaxOpsPerSecond)
+	}
+	return ""
+}
+
+// parseInfoField returns integer value of the field from output of INFO command.
+func parseInfoField(info string, field string) (int64, bool) {
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, field+":") {
+			continue
+		}
+
+		value, err := strconv.ParseInt(line[len(field)+1:], 10, 64)
+		return value, err == nil
+	}
+	return 0, false
+}
+
+// DescribeThrottling returns throttle state of the most throttled node, empty if none is throttled.
+func DescribeThrottling(shards []*Shard) string {
+	state := ""
+	var maxDelay time.Duration = -1
+	for _, shard := range shards {
+		if shard.throttler == nil {
+			continue
+		}
+
+		shard.throttler.mutex.Lock()
+		delay := shard.throttler.adaptiveDelay
+		shard.throttler.mutex.Unlock()
+		if delay <= maxDelay {
+			continue
+		}
+
+		maxDelay = delay
+		state = shard.throttler.State()
+		if len(shards) > 1 && delay > 0 {
+			state = shard.Client.Options().Addr + " " + state
+		}
+	}
+	return state
+}
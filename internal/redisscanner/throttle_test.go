@@ -0,0 +1,140 @@
+package redisscanner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+func TestParseInfoField(t *testing.T) {
+	info := "# Stats\r\n" +
+		"total_connections_received:12\r\n" +
+		"instantaneous_ops_per_sec:2048\r\n" +
+		"instantaneous_input_kbps:1.25\r\n"
+
+	testCases := []struct {
+		field    string
+		expected int64
+		found    bool
+	}{
+		{"instantaneous_ops_per_sec", 2048, true},
+		{"total_connections_received", 12, true},
+		{"instantaneous_input_kbps", 0, false},
+		{"instantaneous_ops", 0, false},
+		{"rejected_connections", 0, false},
+	}
+
+	for _, testCase := range testCases {
+		value, found := parseInfoField(info, testCase.field)
+		if value != testCase.expected || found != testCase.found {
+			t.Errorf(
+				"Incorrect value of %s. Expected %d (found %t), got %d (found %t)",
+				testCase.field,
+				testCase.expected,
+				testCase.found,
+				value,
+				found,
+			)
+		}
+	}
+}
+
+func TestScanShardsSharesThrottlerPerNode(t *testing.T) {
+	shards := []*Shard{
+		{Name: "127.0.0.1:1/db0", Client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DB: 0, MaxRetries: -1})},
+		{Name: "127.0.0.1:1/db1", Client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DB: 1, MaxRetries: -1})},
+		{Name: "127.0.0.1:2", Client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:2", MaxRetries: -1})},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	keyReceiver := make(chan KeyRecord)
+	ScanShards(ctx, shards, ScanOptions{Throttle: ThrottleOptions{MaxOpsPerSecond: 10}}, keyReceiver)
+	for range keyReceiver {
+	}
+
+	if shards[0].throttler == nil || shards[0].throttler != shards[1].throttler {
+		t.Error("Databases of the same node must share the throttler")
+	}
+	if shards[2].throttler == nil || shards[2].throttler == shards[0].throttler {
+		t.Error("Different nodes must have separate throttlers")
+	}
+}
+
+func TestThrottlerBackoffAndRecover(t *testing.T) {
+	throttler := newThrottler(nil, ThrottleOptions{Adaptive: true, LatencyThreshold: 100 * time.Millisecond})
+
+	expectedDelays := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+	}
+	for _, expected := range expectedDelays {
+		throttler.Observe(200 * time.Millisecond)
+		if throttler.adaptiveDelay != expected {
+			t.Fatalf("Incorrect delay after slow SCAN. Expected %v, got %v", expected, throttler.adaptiveDelay)
+		}
+	}
+	if state := throttler.State(); state != "backing off 40ms per SCAN (SCAN latency 200ms)" {
+		t.Errorf("Incorrect throttle state %q", state)
+	}
+
+	for i := 0; i < 20; i++ {
+		throttler.Observe(time.Second)
+	}
+	if throttler.adaptiveDelay != maxAdaptiveDelay {
+		t.Errorf("Delay must be capped at %v, got %v", maxAdaptiveDelay, throttler.adaptiveDelay)
+	}
+
+	throttler.serverOverloads = true
+	throttler.Observe(time.Millisecond)
+	if throttler.adaptiveDelay != maxAdaptiveDelay {
+		t.Errorf("Delay must not recover while server overloads, got %v", throttler.adaptiveDelay)
+	}
+
+	throttler.serverOverloads = false
+	throttler.Observe(time.Millisecond)
+	if throttler.adaptiveDelay != maxAdaptiveDelay/2 {
+		t.Errorf("Incorrect delay after fast SCAN. Expected %v, got %v", maxAdaptiveDelay/2, throttler.adaptiveDelay)
+	}
+
+	for i := 0; i < 20; i++ {
+		throttler.Observe(time.Millisecond)
+	}
+	if throttler.adaptiveDelay != 0 || throttler.State() != "" {
+		t.Errorf("Throttler must fully recover, got delay %v with state %q", throttler.adaptiveDelay, throttler.State())
+	}
+}
+
+func TestThrottlerIgnoresLatencyUnlessAdaptive(t *testing.T) {
+	throttler := newThrottler(nil, ThrottleOptions{MaxOpsPerSecond: 50, LatencyThreshold: time.Millisecond})
+	throttler.Observe(time.Second)
+	if throttler.adaptiveDelay != 0 {
+		t.Errorf("Non-adaptive throttler must not back off, got delay %v", throttler.adaptiveDelay)
+	}
+	if state := throttler.State(); state != "limited to 50 SCAN/s" {
+		t.Errorf("Incorrect throttle state %q", state)
+	}
+}
+
+func TestThrottlerWaitLimitsRate(t *testing.T) {
+	throttler := newThrottler(nil, ThrottleOptions{MaxOpsPerSecond: 20})
+
+	startTime := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := throttler.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(startTime); elapsed < 100*time.Millisecond {
+		t.Errorf("Three calls limited to 20 per second must take at least %v, took %v", 100*time.Millisecond, elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := throttler.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait must be interrupted by the context, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package redisscanner
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+)
+
+// TLSOptions controls TLS used while connecting to redis.
+// TLS is enabled either by rediss:// URL scheme or by setting Enabled.
+type TLSOptions struct {
+	Enabled    bool
+	CACert     string
+	Cert       string
+	Key        string
+	ServerName string
+	SkipVerify bool
+}
+
+// apply enables TLS on top of given config if it's required by the options.
+// Config is nil when TLS isn't used.
+func (tlsOptions TLSOptions) apply(config *tls.Config) (*tls.Config, error) {
+	if config == nil {
+		if !tlsOptions.Enabled {
+			return nil, nil
+		}
+		config = &tls.Config{}
+	}
+
+	if tlsOptions.CACert != "" {
+		pem, err := ioutil.ReadFile(tlsOptions.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read CA bundle: %v", err)
+		}
+
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in CA bundle %s", tlsOptions.CACert)
+		}
+		config.RootCAs = certPool
+	}
+
+	if tlsOptions.Cert != "" || tlsOptions.Key != "" {
+		if tlsOptions.Cert == "" || tlsOptions.Key == "" {
+			return nil, errors.New("Both client certificate and key are required for client authentication")
+		}
+
+		certificate, err := tls.LoadX509KeyPair(tlsOptions.Cert, tlsOptions.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	if tlsOptions.ServerName != "" {
+		config.ServerName = tlsOptions.ServerName
+	}
+	config.InsecureSkipVerify = tlsOptions.SkipVerify
+	return config, nil
+}
+
+// forNode returns config verifying certificate of a node against its own address, unless server name
+// was given explicitly. Config is copied, so that clients of the other nodes aren't affected.
+func (tlsOptions TLSOptions) forNode(config *tls.Config) *tls.Config {
+	if config == nil || tlsOptions.ServerName != "" {
+		return config
+	}
+
+	config = config.Clone()
+	config.ServerName = ""
+	return config
+}
+
+// describeConnectionError tells whether connecting to the address failed during
+// TCP dial, TLS handshake, authentication or the ping itself.
+func describeConnectionError(addr string, usesTLS bool, err error) error {
+	message := err.Error()
+	if usesTLS && err == io.EOF {
+		return fmt.Errorf("TLS handshake with %s failed, server closed the connection", addr)
+	}
+
+	switch err.(type) {
+	case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError, tls.RecordHeaderError:
+		return fmt.Errorf("TLS handshake with %s failed: %v", addr, err)
+	}
+	if strings.HasPrefix(message, "tls: ") || strings.HasPrefix(message, "x509: ") {
+		return fmt.Errorf("TLS handshake with %s failed: %v", addr, err)
+	}
+
+	for _, prefix := range []string{"NOAUTH", "WRONGPASS", "NOPERM", "ERR invalid password", "ERR AUTH", "ERR Client sent AUTH"} {
+		if strings.HasPrefix(message, prefix) {
+			return fmt.Errorf("Authentication to %s failed: %v", addr, err)
+		}
+	}
+
+	if _, ok := err.(*net.OpError); ok {
+		return fmt.Errorf("Unable to connect to %s: %v", addr, err)
+	}
+	return fmt.Errorf("Ping to %s failed: %v", addr, err)
+}
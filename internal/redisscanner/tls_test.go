@@ -0,0 +1,58 @@
+package redisscanner
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestDescribeConnectionError(t *testing.T) {
+	testCases := []struct {
+		usesTLS bool
+		err     error
+		prefix  string
+	}{
+		{true, io.EOF, "TLS handshake with redis:6379 failed, server closed the connection"},
+		{false, io.EOF, "Ping to redis:6379 failed"},
+		{true, x509.UnknownAuthorityError{}, "TLS handshake with redis:6379 failed"},
+		{true, x509.HostnameError{Certificate: &x509.Certificate{}, Host: "redis"}, "TLS handshake with redis:6379 failed"},
+		{true, tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, "TLS handshake with redis:6379 failed"},
+		{true, errors.New("tls: handshake failure"), "TLS handshake with redis:6379 failed"},
+		{false, errors.New("NOAUTH Authentication required."), "Authentication to redis:6379 failed"},
+		{false, errors.New("WRONGPASS invalid username-password pair"), "Authentication to redis:6379 failed"},
+		{false, errors.New("ERR invalid password"), "Authentication to redis:6379 failed"},
+		{false, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, "Unable to connect to redis:6379"},
+		{false, errors.New("LOADING Redis is loading the dataset in memory"), "Ping to redis:6379 failed"},
+	}
+
+	for _, testCase := range testCases {
+		message := describeConnectionError("redis:6379", testCase.usesTLS, testCase.err).Error()
+		if !strings.HasPrefix(message, testCase.prefix) {
+			t.Errorf("Incorrect description of error %q. Expected prefix %q, got %q", testCase.err, testCase.prefix, message)
+		}
+	}
+}
+
+func TestTLSForNode(t *testing.T) {
+	if config := (TLSOptions{}).forNode(nil); config != nil {
+		t.Error("Config of connections without TLS must stay nil")
+	}
+
+	urlConfig := &tls.Config{ServerName: "seed.example.com"}
+	nodeConfig := (TLSOptions{}).forNode(urlConfig)
+	if nodeConfig.ServerName != "" {
+		t.Errorf("Node must be verified against its own address, got server name %q", nodeConfig.ServerName)
+	}
+	if urlConfig.ServerName != "seed.example.com" {
+		t.Error("Config of the seed node must not be modified")
+	}
+
+	explicitConfig := &tls.Config{ServerName: "redis.example.com"}
+	if config := (TLSOptions{ServerName: "redis.example.com"}).forNode(explicitConfig); config.ServerName != "redis.example.com" {
+		t.Errorf("Explicit server name must be kept, got %q", config.ServerName)
+	}
+}
@@ -0,0 +1,301 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-redis/redis"
+	"gopkg.in/yaml.v2"
+
+	"github.com/Ashish-Bansal/redis-spectacles/internal/keystats"
+	"github.com/Ashish-Bansal/redis-spectacles/internal/redisscanner"
+)
+
+// TTL policies of the rules, keys of rules without policy may or may not expire.
+const (
+	TTLPolicyRequired = "required"
+	TTLPolicyNone     = "none"
+)
+
+// Rule classifies keys matching either the glob or the regex.
+type Rule struct {
+	Name  string `yaml:"name"`
+	Owner string `yaml:"owner"`
+
+	// Glob is redis style pattern e.g. "s:*", which is matched against whole key.
+	Glob string `yaml:"glob"`
+	// Regex is matched anywhere in the key unless it's anchored.
+	Regex string `yaml:"regex"`
+
+	// TTL is the expected TTL policy of the keys, either TTLPolicyRequired or TTLPolicyNone.
+	TTL string `yaml:"ttl"`
+
+	// MaxKeys is the maximum number of keys expected to match the rule, 0 means no limit.
+	MaxKeys int64 `yaml:"max_keys"`
+	// Types lists allowed types of the keys, keys of any type are allowed if it's empty.
+	Types []string `yaml:"types"`
+
+	pattern *regexp.Regexp
+}
+
+// RuleSet is ordered list of the rules, key is classified by the first rule it matches.
+type RuleSet struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Load reads the rules from YAML file.
+func Load(path string) (*RuleSet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleSet, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid rules file %s: %v", path, err)
+	}
+	return ruleSet, nil
+}
+
+// Parse decodes the rules from YAML and compiles their patterns.
+func Parse(data []byte) (*RuleSet, error) {
+	ruleSet := &RuleSet{}
+	if err := yaml.UnmarshalStrict(data, ruleSet); err != nil {
+		return nil, err
+	}
+
+	for index, rule := range ruleSet.Rules {
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("rule %d: %v", index+1, err)
+		}
+	}
+	return ruleSet, nil
+}
+
+func (rule *Rule) compile() error {
+	if rule.Name == "" {
+		return errors.New("name is required")
+	}
+	if (rule.Glob == "") == (rule.Regex == "") {
+		return fmt.Errorf("%s must have either glob or regex", rule.Name)
+	}
+	if rule.TTL != "" && rule.TTL != TTLPolicyRequired && rule.TTL != TTLPolicyNone {
+		return fmt.Errorf("%s has unknown TTL policy %q", rule.Name, rule.TTL)
+	}
+	for _, keyType := range rule.Types {
+		if keystats.KeyTypes[keystats.TypeIndex(keyType)] != keyType {
+			return fmt.Errorf("%s allows unknown type %q", rule.Name, keyType)
+		}
+	}
+
+	expression := rule.Regex
+	if rule.Glob != "" {
+		expression = globToRegex(rule.Glob)
+	}
+
+	pattern, err := regexp.Compile(expression)
+	if err != nil {
+		return fmt.Errorf("%s has invalid pattern: %v", rule.Name, err)
+	}
+	rule.pattern = pattern
+	return nil
+}
+
+// globToRegex converts redis style glob supporting *, ?, [...] and \ escapes into anchored regex.
+// Glob is iterated by runes, so that multi-byte characters are kept as they are.
+func globToRegex(glob string) string {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for index := 0; index < len(glob); {
+		character, size := utf8.DecodeRuneInString(glob[index:])
+		switch character {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		case '\\':
+			if index+size < len(glob) {
+				_, escapedSize := utf8.DecodeRuneInString(glob[index+size:])
+				builder.WriteString(regexp.QuoteMeta(glob[index+size : index+size+escapedSize]))
+				size += escapedSize
+			}
+		case '[':
+			end := strings.IndexByte(glob[index+1:], ']')
+			if end < 0 {
+				builder.WriteString(`\[`)
+				break
+			}
+
+			class := glob[index+1 : index+1+end]
+			if strings.HasPrefix(class, "^") {
+				class = "^" + strings.Replace(class[1:], `\`, `\\`, -1)
+			} else {
+				class = strings.Replace(class, `\`, `\\`, -1)
+			}
+			builder.WriteString("[" + class + "]")
+			size = end + 2
+		default:
+			builder.WriteString(regexp.QuoteMeta(glob[index : index+size]))
+		}
+		index += size
+	}
+	builder.WriteString("$")
+	return builder.String()
+}
+
+// Matches returns whether the key matches pattern of the rule.
+func (rule *Rule) Matches(key string) bool {
+	return rule.pattern.MatchString(key)
+}
+
+// Classify returns index of the first rule matching the key, -1 if the key is unclassified.
+func (ruleSet *RuleSet) Classify(key string) int {
+	for index, rule := range ruleSet.Rules {
+		if rule.Matches(key) {
+			return index
+		}
+	}
+	return -1
+}
+
+// RequiresTTL returns whether any rule has TTL policy, in which case TTL of the keys must be collected.
+func (ruleSet *RuleSet) RequiresTTL() bool {
+	for _, rule := range ruleSet.Rules {
+		if rule.TTL != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresTypes returns whether any rule restricts types of the keys, in which case types must be collected.
+func (ruleSet *RuleSet) RequiresTypes() bool {
+	for _, rule := range ruleSet.Rules {
+		if len(rule.Types) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// violatesTTLPolicy returns whether key with given stats doesn't follow TTL policy of the rule.
+// Keys whose TTL is unknown don't violate any policy.
+func (rule *Rule) violatesTTLPolicy(stats keystats.KeyStats) bool {
+	switch rule.TTL {
+	case TTLPolicyRequired:
+		return stats.NoTTLKeys > 0
+	case TTLPolicyNone:
+		return stats.TTLKeys() > stats.NoTTLKeys
+	}
+	return false
+}
+
+// violatesTypes returns whether key with given stats is of type not allowed by the rule.
+// Keys whose type is unknown don't violate it.
+func (rule *Rule) violatesTypes(stats keystats.KeyStats) bool {
+	if len(rule.Types) == 0 {
+		return false
+	}
+
+	for index, count := range stats.TypeCounts {
+		if count == 0 {
+			continue
+		}
+
+		allowed := false
+		for _, keyType := range rule.Types {
+			allowed = allowed || keystats.KeyTypes[index] == keyType
+		}
+		if !allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate returns stats of the single key matching the rule.
+func (rule *Rule) evaluate(stats keystats.KeyStats) keystats.RuleStats {
+	ruleStats := keystats.RuleStats{Keys: 1}
+	if rule.violatesTTLPolicy(stats) {
+		ruleStats.TTLViolations = 1
+	}
+	if rule.violatesTypes(stats) {
+		ruleStats.TypeViolations = 1
+	}
+	return ruleStats
+}
+
+// Queue classifies the records once other collectors have filled their attributes, it sends no commands.
+// It makes RuleSet a redisscanner.Collector, which must be added after the TTL is collected.
+func (ruleSet *RuleSet) Queue(pipeline redis.Pipeliner, records []redisscanner.KeyRecord) func() {
+	return func() {
+		for index := range records {
+			stats := &records[index].Stats
+			if ruleIndex := ruleSet.Classify(records[index].Key); ruleIndex >= 0 {
+				stats.SetRule(ruleIndex, ruleSet.Rules[ruleIndex].evaluate(*stats))
+			}
+		}
+	}
+}
+
+// MatchedNames returns names of the rules matching any of the keys with given stats, empty if there's none.
+func (ruleSet *RuleSet) MatchedNames(stats keystats.KeyStats) string {
+	names := make([]string, 0)
+	for index, ruleStats := range stats.Rules {
+		if ruleStats.Keys > 0 {
+			names = append(names, ruleSet.Rules[index].Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// DescribeMatches returns number of keys matching each rule along with its owner e.g. "sessions (Auth team) 120".
+func (ruleSet *RuleSet) DescribeMatches(stats keystats.KeyStats) string {
+	parts := make([]string, 0)
+	for index, ruleStats := range stats.Rules {
+		if ruleStats.Keys == 0 {
+			continue
+		}
+
+		rule := ruleSet.Rules[index]
+		part := rule.Name
+		if rule.Owner != "" {
+			part += " (" + rule.Owner + ")"
+		}
+		parts = append(parts, fmt.Sprintf("%s %d", part, ruleStats.Keys))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Summary is rollup of the keys classified by single rule, Rule is nil for the unclassified keys.
+type Summary struct {
+	Rule *Rule
+	keystats.RuleStats
+}
+
+// Summarize returns rollup of every rule followed by the unclassified keys, given stats and count of all the keys.
+func (ruleSet *RuleSet) Summarize(stats keystats.KeyStats, keyCount int) []Summary {
+	summaries := make([]Summary, 0, len(ruleSet.Rules)+1)
+	unclassifiedKeys := int64(keyCount)
+	for index, rule := range ruleSet.Rules {
+		summary := Summary{Rule: rule}
+		if index < len(stats.Rules) {
+			summary.RuleStats = stats.Rules[index]
+		}
+		unclassifiedKeys -= summary.Keys
+		summaries = append(summaries, summary)
+	}
+	return append(summaries, Summary{RuleStats: keystats.RuleStats{Keys: unclassifiedKeys}})
+}
+
+// Name returns name of the rule, "unclassified" for the unclassified keys.
+func (summary Summary) Name() string {
+	if summary.Rule == nil {
+		return "unclassified"
+	}
+	return summary.Rule.Name
+}
@@ -0,0 +1,169 @@
+package rules
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Ashish-Bansal/redis-spectacles/internal/keystats"
+)
+
+func TestGlobToRegex(t *testing.T) {
+	testCases := []struct {
+		glob       string
+		expression string
+		matches    []string
+		mismatches []string
+	}{
+		{"s:*", `^s:.*$`, []string{"s:", "s:123"}, []string{"s", "xs:1"}},
+		{"user:?", `^user:.$`, []string{"user:1", "user:é"}, []string{"user:", "user:12"}},
+		{"h[ae]llo", `^h[ae]llo$`, []string{"hallo", "hello"}, []string{"hillo"}},
+		{"h[^e]llo", `^h[^e]llo$`, []string{"hallo"}, []string{"hello"}},
+		{`a\*b`, `^a\*b$`, []string{"a*b"}, []string{"axb"}},
+		{"v1.0[", `^v1\.0\[$`, []string{"v1.0["}, []string{"v1x0["}},
+		{"ключ:*", `^ключ:.*$`, []string{"ключ:1"}, []string{"key:1"}},
+		{"café:*", `^café:.*$`, []string{"café:menu"}, []string{"cafe:menu"}},
+		{`\é*`, `^é.*$`, []string{"é1"}, []string{"e1"}},
+	}
+
+	for _, testCase := range testCases {
+		expression := globToRegex(testCase.glob)
+		if expression != testCase.expression {
+			t.Errorf("Regex of glob %q didn't match. Expected %s, got %s.", testCase.glob, testCase.expression, expression)
+			continue
+		}
+
+		pattern := regexp.MustCompile(expression)
+		for _, key := range testCase.matches {
+			if !pattern.MatchString(key) {
+				t.Errorf("Glob %q must match key %q", testCase.glob, key)
+			}
+		}
+		for _, key := range testCase.mismatches {
+			if pattern.MatchString(key) {
+				t.Errorf("Glob %q must not match key %q", testCase.glob, key)
+			}
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	ruleSet, err := Parse([]byte(`
+rules:
+  - name: sessions
+    glob: "s:*"
+  - name: carts
+    regex: "^user:[0-9]+:cart$"
+  - name: users
+    glob: "user:*"
+  - name: caches
+    regex: "cache"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		key      string
+		expected int
+	}{
+		{"s:abc", 0},
+		{"user:42:cart", 1},
+		{"user:42:profile", 2},
+		{"page:cache:home", 3},
+		{"order:1", -1},
+		{"", -1},
+	}
+
+	for _, testCase := range testCases {
+		if actual := ruleSet.Classify(testCase.key); actual != testCase.expected {
+			t.Errorf("Incorrect rule of key %q. Expected %d, got %d.", testCase.key, testCase.expected, actual)
+		}
+	}
+}
+
+func TestParseInvalidRules(t *testing.T) {
+	testCases := []string{
+		`rules: [{glob: "s:*"}]`,
+		`rules: [{name: sessions}]`,
+		`rules: [{name: sessions, glob: "s:*", regex: "^s:"}]`,
+		`rules: [{name: sessions, glob: "s:*", ttl: sometimes}]`,
+		`rules: [{name: sessions, glob: "s:*", types: [document]}]`,
+		`rules: [{name: sessions, regex: "("}]`,
+		`rules: [{name: sessions, glob: "s:*", unknown: field}]`,
+	}
+
+	for _, testCase := range testCases {
+		if _, err := Parse([]byte(testCase)); err == nil {
+			t.Errorf("Rules %s must be rejected", testCase)
+		}
+	}
+}
+
+func keyStats(ttl time.Duration, keyType string) keystats.KeyStats {
+	stats := keystats.KeyStats{}
+	if ttl != 0 {
+		stats.SetTTL(ttl)
+	}
+	if keyType != "" {
+		stats.SetType(keyType)
+	}
+	return stats
+}
+
+func TestViolatesTTLPolicy(t *testing.T) {
+	testCases := []struct {
+		policy   string
+		ttl      time.Duration
+		violates bool
+	}{
+		{TTLPolicyRequired, time.Hour, false},
+		{TTLPolicyRequired, -1, true},
+		{TTLPolicyRequired, 0, false},
+		{TTLPolicyNone, time.Hour, true},
+		{TTLPolicyNone, -1, false},
+		{TTLPolicyNone, 0, false},
+		{"", time.Hour, false},
+		{"", -1, false},
+	}
+
+	for _, testCase := range testCases {
+		rule := &Rule{TTL: testCase.policy}
+		if actual := rule.violatesTTLPolicy(keyStats(testCase.ttl, "")); actual != testCase.violates {
+			t.Errorf(
+				"Incorrect violation of TTL policy %q by key with TTL %v. Expected %t, got %t.",
+				testCase.policy,
+				testCase.ttl,
+				testCase.violates,
+				actual,
+			)
+		}
+	}
+}
+
+func TestViolatesTypes(t *testing.T) {
+	testCases := []struct {
+		types    []string
+		keyType  string
+		violates bool
+	}{
+		{nil, "hash", false},
+		{[]string{"hash"}, "hash", false},
+		{[]string{"string", "hash"}, "hash", false},
+		{[]string{"string"}, "hash", true},
+		{[]string{"string"}, "", false},
+	}
+
+	for _, testCase := range testCases {
+		rule := &Rule{Types: testCase.types}
+		if actual := rule.violatesTypes(keyStats(0, testCase.keyType)); actual != testCase.violates {
+			t.Errorf(
+				"Incorrect violation of types %v by key of type %q. Expected %t, got %t.",
+				testCase.types,
+				testCase.keyType,
+				testCase.violates,
+				actual,
+			)
+		}
+	}
+}
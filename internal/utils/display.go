@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Display modes of the keys, raw mode shows keys as they are, escaped mode replaces non-printable
+// characters and invalid UTF-8 with escape sequences e.g. "\x00" and hex mode shows every byte in hex.
+const (
+	DisplayRaw     = "raw"
+	DisplayEscaped = "escaped"
+	DisplayHex     = "hex"
+)
+
+// KeyFormatter formats keys and their prefixes for display.
+type KeyFormatter func(key string) string
+
+// NewKeyFormatter returns formatter of given display mode.
+func NewKeyFormatter(mode string) (KeyFormatter, error) {
+	switch mode {
+	case DisplayRaw:
+		return func(key string) string {
+			return key
+		}, nil
+	case DisplayEscaped:
+		return EscapeKey, nil
+	case DisplayHex:
+		return func(key string) string {
+			return hex.EncodeToString([]byte(key))
+		}, nil
+	default:
+		return nil, fmt.Errorf("Unknown display mode %q", mode)
+	}
+}
+
+// EscapeKey replaces bytes which aren't valid UTF-8 with "\xNN" and non-printable characters with
+// "\xNN", "\uNNNN" or "\UNNNNNNNN" escape sequences, so that binary keys can be safely printed.
+// Backslash itself is escaped as "\\", so that escape sequences can't be confused with the text of the key.
+func EscapeKey(key string) string {
+	var builder strings.Builder
+	for index := 0; index < len(key); {
+		character, size := utf8.DecodeRuneInString(key[index:])
+		switch {
+		case character == utf8.RuneError && size == 1:
+			fmt.Fprintf(&builder, `\x%02x`, key[index])
+		case character == '\\':
+			builder.WriteString(`\\`)
+		case character < utf8.RuneSelf && !unicode.IsPrint(character):
+			fmt.Fprintf(&builder, `\x%02x`, character)
+		case !unicode.IsPrint(character) && character <= 0xffff:
+			fmt.Fprintf(&builder, `\u%04x`, character)
+		case !unicode.IsPrint(character):
+			fmt.Fprintf(&builder, `\U%08x`, character)
+		default:
+			builder.WriteString(key[index : index+size])
+		}
+		index += size
+	}
+	return builder.String()
+}
@@ -0,0 +1,53 @@
+package utils
+
+import "testing"
+
+func TestEscapeKey(t *testing.T) {
+	testCases := []struct {
+		key      string
+		expected string
+	}{
+		{"user:1", "user:1"},
+		{"bin:\x00\xff", `bin:\x00\xff`},
+		{`bin:\x00\xff`, `bin:\\x00\\xff`},
+		{`a\b`, `a\\b`},
+		{"tab\there", `tab\x09here`},
+		{"ключ:é", "ключ:é"},
+		{"zero\u200bwidth", `zero\u200bwidth`},
+		{"tag\U000e0001", `tag\U000e0001`},
+		{"\xc3", `\xc3`},
+		{"", ""},
+	}
+
+	for _, testCase := range testCases {
+		if actual := EscapeKey(testCase.key); actual != testCase.expected {
+			t.Errorf("Escaped key of %q didn't match. Expected %s, got %s.", testCase.key, testCase.expected, actual)
+		}
+	}
+}
+
+func TestNewKeyFormatter(t *testing.T) {
+	testCases := []struct {
+		mode     string
+		key      string
+		expected string
+	}{
+		{DisplayRaw, "bin:\x00", "bin:\x00"},
+		{DisplayEscaped, "bin:\x00", `bin:\x00`},
+		{DisplayHex, "bin:\x00", "62696e3a00"},
+	}
+
+	for _, testCase := range testCases {
+		formatKey, err := NewKeyFormatter(testCase.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := formatKey(testCase.key); actual != testCase.expected {
+			t.Errorf("Key %q formatted in %s mode didn't match. Expected %q, got %q.", testCase.key, testCase.mode, testCase.expected, actual)
+		}
+	}
+
+	if _, err := NewKeyFormatter("base64"); err == nil {
+		t.Error("Unknown display mode must be rejected")
+	}
+}
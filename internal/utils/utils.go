@@ -1,5 +1,14 @@
 package utils
 
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
 // Min - a super complex function which golang authors never thought of implementing.
 func Min(a, b int) int {
 	if a < b {
@@ -15,3 +24,71 @@ func Max(a, b int) int {
 	}
 	return b
 }
+
+// Min64 - same as Min, but for int64 values.
+func Min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Max64 - same as Max, but for int64 values.
+func Max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// NewInterruptibleContext returns context which is cancelled on SIGINT/SIGTERM
+// or once max duration has elapsed. Zero max duration means no time limit.
+func NewInterruptibleContext(maxDuration time.Duration) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if maxDuration > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), maxDuration)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
+
+// DescribeInterruption returns reason due to which context got done, empty if it's still active.
+func DescribeInterruption(ctx context.Context) string {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return "max duration reached"
+	case context.Canceled:
+		return "scan interrupted"
+	default:
+		return ""
+	}
+}
+
+// FormatBytes returns human readable size e.g. 1.5M for given number of bytes.
+func FormatBytes(bytes int64) string {
+	if bytes < 1024 {
+		return fmt.Sprintf("%dB", bytes)
+	}
+
+	size := float64(bytes)
+	for _, unit := range []string{"K", "M", "G", "T"} {
+		size /= 1024
+		if size < 1024 {
+			return fmt.Sprintf("%.1f%s", size, unit)
+		}
+	}
+	return fmt.Sprintf("%.1fP", size/1024)
+}
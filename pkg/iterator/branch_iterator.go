@@ -0,0 +1,34 @@
+package iterator
+
+// Branched represents item which is iterated after yielding Branch as single element,
+// e.g. to put keys of a database under their own top-level branch of the trie regardless of the tokenizer.
+type Branched struct {
+	Branch string
+	Item   interface{}
+}
+
+type branchIterator struct {
+	branch   string
+	yielded  bool
+	iterator Iterator
+}
+
+func (it *branchIterator) HasNext() bool {
+	return !it.yielded || it.iterator.HasNext()
+}
+
+func (it *branchIterator) Next() (interface{}, error) {
+	if !it.yielded {
+		it.yielded = true
+		return it.branch, nil
+	}
+	return it.iterator.Next()
+}
+
+func getBranchIterator(branched Branched) (Iterator, error) {
+	iterator, err := NewIterator(branched.Item)
+	if err != nil {
+		return nil, err
+	}
+	return &branchIterator{branch: branched.Branch, iterator: iterator}, nil
+}
@@ -0,0 +1,44 @@
+package iterator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBranchIteratorValues(t *testing.T) {
+	testCases := []struct {
+		item     interface{}
+		expected []string
+	}{
+		{"ab", []string{"db1/", "a", "b"}},
+		{Bytes("ab"), []string{"db1/", "a", "b"}},
+		{Segments{Str: "user:1", Delimiters: ":"}, []string{"db1/", "user:", "1"}},
+		{"", []string{"db1/"}},
+	}
+
+	for _, testCase := range testCases {
+		values := make([]string, 0)
+		it, err := NewIterator(Branched{Branch: "db1/", Item: testCase.item})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for it.HasNext() {
+			value, _ := it.Next()
+			values = append(values, value.(string))
+		}
+
+		if !reflect.DeepEqual(testCase.expected, values) {
+			t.Errorf("Iterator values didn't match. Expected %q, got %q.", testCase.expected, values)
+		}
+
+		if _, err := it.Next(); err != ErrEndOfContainer {
+			t.Errorf("Iterator must return ErrEndOfContainer once item is exhausted, got %v.", err)
+		}
+	}
+}
+
+func TestBranchIteratorUnknownItem(t *testing.T) {
+	if _, err := NewIterator(Branched{Branch: "db1/", Item: 1}); err == nil {
+		t.Error("Iterator must not be created for item which can't be iterated")
+	}
+}
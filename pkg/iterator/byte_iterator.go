@@ -0,0 +1,26 @@
+package iterator
+
+// Bytes represents string which is iterated byte by byte, each byte being yielded as single byte string.
+// Unlike characters, bytes make sense for binary keys e.g. msgpack or protobuf encoded ones.
+type Bytes string
+
+type byteIterator struct {
+	str   string
+	index int
+}
+
+func (it *byteIterator) HasNext() bool {
+	return it.index < len(it.str)
+}
+
+func (it *byteIterator) Next() (interface{}, error) {
+	if !it.HasNext() {
+		return nil, ErrEndOfContainer
+	}
+	it.index++
+	return it.str[it.index-1 : it.index], nil
+}
+
+func getByteIterator(bytes Bytes) Iterator {
+	return &byteIterator{str: string(bytes)}
+}
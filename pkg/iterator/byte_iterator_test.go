@@ -0,0 +1,26 @@
+package iterator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestByteIteratorValues(t *testing.T) {
+	testString := "\x82\xa3é"
+	testStringBytes := []string{"\x82", "\xa3", "\xc3", "\xa9"}
+
+	bytes := make([]string, 0)
+	it, _ := NewIterator(Bytes(testString))
+	for it.HasNext() {
+		b, _ := it.Next()
+		bytes = append(bytes, b.(string))
+	}
+
+	if !reflect.DeepEqual(testStringBytes, bytes) {
+		t.Errorf("Iterator values didn't match. Expected %q, got %q.", testStringBytes, bytes)
+	}
+
+	if _, err := it.Next(); err != ErrEndOfContainer {
+		t.Errorf("Iterator must return ErrEndOfContainer once bytes are exhausted, got %v.", err)
+	}
+}
@@ -21,6 +21,12 @@ func NewIterator(item interface{}) (Iterator, error) {
 	switch item.(type) {
 	case string:
 		return getIterator(item.(string)), nil
+	case Segments:
+		return getSegmentIterator(item.(Segments)), nil
+	case Bytes:
+		return getByteIterator(item.(Bytes)), nil
+	case Branched:
+		return getBranchIterator(item.(Branched))
 	default:
 		return nil, errors.New("Don't know how to iterate")
 	}
@@ -0,0 +1,44 @@
+package iterator
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Segments represents string which is iterated segment by segment, each segment ending with one of the delimiters
+// e.g. "user:1:name" with delimiters ":" yields "user:", "1:" and "name".
+type Segments struct {
+	Str        string
+	Delimiters string
+}
+
+type segmentIterator struct {
+	str        string
+	delimiters string
+	index      int
+}
+
+func (it *segmentIterator) HasNext() bool {
+	return it.index < len(it.str)
+}
+
+func (it *segmentIterator) Next() (interface{}, error) {
+	if !it.HasNext() {
+		return nil, ErrEndOfContainer
+	}
+
+	start := it.index
+	end := strings.IndexAny(it.str[start:], it.delimiters)
+	if end < 0 {
+		it.index = len(it.str)
+	} else {
+		// Delimiter is kept at the end of the segment, so that segments concatenate back into the string.
+		_, delimiterSize := utf8.DecodeRuneInString(it.str[start+end:])
+		it.index = start + end + delimiterSize
+	}
+	return it.str[start:it.index], nil
+}
+
+func getSegmentIterator(segments Segments) Iterator {
+	return &segmentIterator{str: segments.Str, delimiters: segments.Delimiters}
+}
@@ -0,0 +1,70 @@
+package iterator
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func ExampleSegments() {
+	it, _ := NewIterator(Segments{Str: "user:42/name", Delimiters: ":/"})
+	for it.HasNext() {
+		segment, _ := it.Next()
+		fmt.Printf("%s\n", segment)
+	}
+	// Output:
+	// user:
+	// 42/
+	// name
+}
+
+func TestSegmentIteratorValues(t *testing.T) {
+	testCases := []struct {
+		str        string
+		delimiters string
+		segments   []string
+	}{
+		{"user:1:name", ":", []string{"user:", "1:", "name"}},
+		{"a::b:", ":", []string{"a:", ":", "b:"}},
+		{"cache.v2|item", DefaultDelimiters, []string{"cache.", "v2|", "item"}},
+		{"plain", ":", []string{"plain"}},
+		{"ключ→значение", "→", []string{"ключ→", "значение"}},
+		{"", ":", []string{}},
+	}
+
+	for _, testCase := range testCases {
+		segments := make([]string, 0)
+		it := getSegmentIterator(Segments{Str: testCase.str, Delimiters: testCase.delimiters})
+		for it.HasNext() {
+			segment, _ := it.Next()
+			segments = append(segments, segment.(string))
+		}
+
+		if !reflect.DeepEqual(testCase.segments, segments) {
+			t.Errorf(
+				"Segments of %q didn't match. Expected %v, got %v.",
+				testCase.str,
+				testCase.segments,
+				segments,
+			)
+		}
+
+		if _, err := it.Next(); err != ErrEndOfContainer {
+			t.Errorf("Iterator must return ErrEndOfContainer once segments are exhausted, got %v.", err)
+		}
+	}
+}
+
+func TestNewTokenizer(t *testing.T) {
+	if _, err := NewTokenizer("word", DefaultDelimiters); err == nil {
+		t.Errorf("Unknown tokenizer mode must be rejected")
+	}
+	if _, err := NewTokenizer(TokenizerSegment, ""); err == nil {
+		t.Errorf("Segment tokenizer without delimiters must be rejected")
+	}
+
+	tokenizer, _ := NewTokenizer(TokenizerCharacter, "")
+	if tokenizer("key") != "key" {
+		t.Errorf("Character tokenizer must keep the key as is")
+	}
+}
@@ -1,7 +1,11 @@
 package iterator
 
+import "unicode/utf8"
+
+// stringIterator yields characters of the string. Bytes which aren't valid UTF-8 are yielded one by one
+// as they are, so that concatenation of the characters always gives back the original string.
 type stringIterator struct {
-	str   []rune
+	str   string
 	index int
 }
 
@@ -13,10 +17,12 @@ func (it *stringIterator) Next() (interface{}, error) {
 	if !it.HasNext() {
 		return nil, ErrEndOfContainer
 	}
-	it.index++
-	return string(it.str[it.index-1]), nil
+	start := it.index
+	_, size := utf8.DecodeRuneInString(it.str[start:])
+	it.index += size
+	return it.str[start:it.index], nil
 }
 
 func getIterator(str string) Iterator {
-	return &stringIterator{str: []rune(str)}
+	return &stringIterator{str: str}
 }
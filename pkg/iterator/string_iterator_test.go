@@ -2,6 +2,7 @@ package iterator
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -34,3 +35,19 @@ func TestIteratorValues(t *testing.T) {
 		testStringCharsIndex++
 	}
 }
+
+func TestIteratorInvalidUTF8(t *testing.T) {
+	testString := "a\xff\x00é"
+	testStringChars := []string{"a", "\xff", "\x00", "é"}
+
+	chars := make([]string, 0)
+	it := getIterator(testString)
+	for it.HasNext() {
+		character, _ := it.Next()
+		chars = append(chars, character.(string))
+	}
+
+	if !reflect.DeepEqual(testStringChars, chars) {
+		t.Errorf("Iterator values didn't match. Expected %q, got %q.", testStringChars, chars)
+	}
+}
@@ -0,0 +1,40 @@
+package iterator
+
+import "fmt"
+
+// Tokenizer modes, segment mode splits keys on delimiters, character mode splits them into runes
+// and byte mode splits them into bytes.
+const (
+	TokenizerSegment   = "segment"
+	TokenizerCharacter = "char"
+	TokenizerByte      = "byte"
+)
+
+// DefaultDelimiters are the delimiters commonly used to namespace keys.
+const DefaultDelimiters = ":/.|"
+
+// Tokenizer converts key into an item whose iterator yields edges of the key in the trie.
+type Tokenizer func(key string) interface{}
+
+// NewTokenizer returns tokenizer of given mode, delimiters are used only by the segment mode.
+func NewTokenizer(mode string, delimiters string) (Tokenizer, error) {
+	switch mode {
+	case TokenizerSegment:
+		if delimiters == "" {
+			return nil, fmt.Errorf("Tokenizer %q requires at least one delimiter", mode)
+		}
+		return func(key string) interface{} {
+			return Segments{Str: key, Delimiters: delimiters}
+		}, nil
+	case TokenizerCharacter:
+		return func(key string) interface{} {
+			return key
+		}, nil
+	case TokenizerByte:
+		return func(key string) interface{} {
+			return Bytes(key)
+		}, nil
+	default:
+		return nil, fmt.Errorf("Unknown tokenizer %q", mode)
+	}
+}
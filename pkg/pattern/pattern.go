@@ -0,0 +1,186 @@
+package pattern
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Placeholders replacing segments of the keys which look like IDs.
+const (
+	Int    = "{int}"
+	UUID   = "{uuid}"
+	Hex    = "{hex}"
+	Base64 = "{base64}"
+	Time   = "{ts}"
+	Email  = "{email}"
+)
+
+var (
+	intRegexp    = regexp.MustCompile(`^-?[0-9]+$`)
+	uuidRegexp   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexRegexp    = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+	base64Regexp = regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`)
+	dateRegexp   = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}([T ][0-9:.]+(Z|[+-][0-9:]+)?)?$`)
+	emailRegexp  = regexp.MustCompile(`^[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}$`)
+
+	// isoTimestampRegexp matches ISO 8601 timestamp at the start of the key, which is split by the delimiters otherwise.
+	isoTimestampRegexp = regexp.MustCompile(
+		`^[0-9]{4}-[0-9]{2}-[0-9]{2}[T ][0-9]{2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?)?(Z|[+-][0-9]{2}(:?[0-9]{2})?)?`,
+	)
+)
+
+// Segments shorter than these lengths are more likely to be words than IDs.
+const (
+	minHexLength    = 8
+	minBase64Length = 16
+)
+
+// Unix timestamps are recognized only within this range, so that other numbers of the same length aren't mistaken.
+var (
+	minTimestamp = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxTimestamp = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// Infer returns placeholder of the segment if it looks like an ID, otherwise returns the segment as is.
+func Infer(segment string) string {
+	switch {
+	case segment == "":
+		return segment
+	case isTimestamp(segment):
+		return Time
+	case intRegexp.MatchString(segment):
+		return Int
+	case uuidRegexp.MatchString(segment):
+		return UUID
+	case len(segment) >= minHexLength && hexRegexp.MatchString(segment) && containsDigit(segment):
+		return Hex
+	case emailRegexp.MatchString(segment):
+		return Email
+	case len(segment) >= minBase64Length && base64Regexp.MatchString(segment) && isMixed(segment):
+		return Base64
+	}
+	return segment
+}
+
+// Generalize replaces segments of the key separated by the delimiters with their placeholders
+// e.g. "user:83731:cart" becomes "user:{int}:cart". Dots inside emails don't split them,
+// so that "user:john.doe@example.com" becomes "user:{email}". Similarly, colons and dots inside
+// ISO timestamps don't split them, so that "event:2024-01-01T10:00:00Z" becomes "event:{ts}".
+func Generalize(key string, delimiters string) string {
+	segmentDelimiters := strings.Replace(delimiters, ".", "", -1)
+	var builder strings.Builder
+	for {
+		if length := isoTimestampLength(key, delimiters); length > 0 {
+			builder.WriteString(Time)
+			key = key[length:]
+		} else {
+			index := len(key)
+			if segmentDelimiters != "" {
+				if delimiterIndex := strings.IndexAny(key, segmentDelimiters); delimiterIndex >= 0 {
+					index = delimiterIndex
+				}
+			}
+			builder.WriteString(generalizeSegment(key[:index], delimiters))
+			key = key[index:]
+		}
+
+		if key == "" {
+			return builder.String()
+		}
+		_, delimiterSize := utf8.DecodeRuneInString(key)
+		builder.WriteString(key[:delimiterSize])
+		key = key[delimiterSize:]
+	}
+}
+
+// generalizeSegment replaces the segment with its placeholder, segment is split on dots
+// unless it's an email or dot isn't one of the delimiters.
+func generalizeSegment(segment string, delimiters string) string {
+	if emailRegexp.MatchString(segment) {
+		return Email
+	}
+	if !strings.Contains(delimiters, ".") {
+		return Infer(segment)
+	}
+
+	var builder strings.Builder
+	for _, part := range split(segment, ".") {
+		builder.WriteString(Infer(part.segment))
+		builder.WriteString(part.delimiter)
+	}
+	return builder.String()
+}
+
+// isoTimestampLength returns length of the ISO timestamp at the start of the key, if it's followed
+// by one of the delimiters or the end of the key. It returns 0 if there's no such timestamp.
+func isoTimestampLength(key string, delimiters string) int {
+	length := len(isoTimestampRegexp.FindString(key))
+	if length == 0 || length == len(key) {
+		return length
+	}
+
+	delimiter, _ := utf8.DecodeRuneInString(key[length:])
+	if !strings.ContainsRune(delimiters, delimiter) {
+		return 0
+	}
+	return length
+}
+
+// part is a segment of the key along with the delimiter following it, empty for the last segment.
+type part struct {
+	segment   string
+	delimiter string
+}
+
+func split(str string, delimiters string) []part {
+	parts := make([]part, 0)
+	if delimiters == "" {
+		return append(parts, part{segment: str})
+	}
+
+	for {
+		index := strings.IndexAny(str, delimiters)
+		if index < 0 {
+			return append(parts, part{segment: str})
+		}
+
+		_, delimiterSize := utf8.DecodeRuneInString(str[index:])
+		delimiterEnd := index + delimiterSize
+		parts = append(parts, part{segment: str[:index], delimiter: str[index:delimiterEnd]})
+		str = str[delimiterEnd:]
+	}
+}
+
+// isTimestamp returns whether segment is a date or unix timestamp in seconds or milliseconds.
+func isTimestamp(segment string) bool {
+	if dateRegexp.MatchString(segment) {
+		return true
+	}
+
+	if len(segment) != 10 && len(segment) != 13 {
+		return false
+	}
+	value, err := strconv.ParseInt(segment, 10, 64)
+	if err != nil {
+		return false
+	}
+	if len(segment) == 13 {
+		value /= 1000
+	}
+	return value >= minTimestamp.Unix() && value < maxTimestamp.Unix()
+}
+
+func containsDigit(segment string) bool {
+	return strings.IndexAny(segment, "0123456789") >= 0
+}
+
+// isMixed returns whether segment contains digits along with both lower and upper case letters,
+// which is typical for base64 encoded data but not for words.
+func isMixed(segment string) bool {
+	hasLower := strings.ToUpper(segment) != segment
+	hasUpper := strings.ToLower(segment) != segment
+	return hasLower && hasUpper && containsDigit(segment)
+}
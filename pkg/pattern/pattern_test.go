@@ -0,0 +1,81 @@
+package pattern
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ExampleGeneralize() {
+	fmt.Println(Generalize("user:83731:cart", ":"))
+	// Output:
+	// user:{int}:cart
+}
+
+func TestInfer(t *testing.T) {
+	testCases := []struct {
+		segment  string
+		expected string
+	}{
+		{"83731", Int},
+		{"-12", Int},
+		{"1700000000", Time},
+		{"1700000000123", Time},
+		{"2024-03-01", Time},
+		{"9999999999", Int},
+		{"123e4567-e89b-12d3-a456-426614174000", UUID},
+		{"9f1c2ab4", Hex},
+		{"5d41402abc4b2a76b9719d911017c592", Hex},
+		{"deadbeef", "deadbeef"},
+		{"john.doe@example.com", Email},
+		{"aGVsbG8gd29ybGQhISE=", Base64},
+		{"dGhpc0lzQVRlc3RLZXk1", Base64},
+		{"cart", "cart"},
+		{"settings", "settings"},
+		{"VeryLongCamelCaseWord", "VeryLongCamelCaseWord"},
+		{"", ""},
+	}
+
+	for _, testCase := range testCases {
+		if actual := Infer(testCase.segment); actual != testCase.expected {
+			t.Errorf(
+				"Inferred pattern of %q didn't match. Expected %s, got %s.",
+				testCase.segment,
+				testCase.expected,
+				actual,
+			)
+		}
+	}
+}
+
+func TestGeneralize(t *testing.T) {
+	testCases := []struct {
+		key        string
+		delimiters string
+		expected   string
+	}{
+		{"user:83731:cart", ":", "user:{int}:cart"},
+		{"order:9f1c2ab4e5:items", ":/.|", "order:{hex}:items"},
+		{"session/123e4567-e89b-12d3-a456-426614174000", ":/.|", "session/{uuid}"},
+		{"user:john.doe@example.com:prefs", ":/.|", "user:{email}:prefs"},
+		{"cache.v2.1700000000", ":/.|", "cache.v2.{ts}"},
+		{"a:2024-01-01T10:00:00Z", ":/.|", "a:{ts}"},
+		{"event:2024-01-01T10:00:00.123+05:30:payload", ":/.|", "event:{ts}:payload"},
+		{"log/2024-01-01 10:00/error", ":/.|", "log/{ts}/error"},
+		{"2024-01-01T10:00:00Z.json", ":/.|", "{ts}.json"},
+		{"user::42:", ":", "user::{int}:"},
+		{"plain", ":", "plain"},
+		{"42", "", Int},
+		{"ключ→42", "→", "ключ→{int}"},
+	}
+
+	for _, testCase := range testCases {
+		if actual := Generalize(testCase.key, testCase.delimiters); actual != testCase.expected {
+			t.Errorf(
+				"Generalized key of %q didn't match. Expected %s, got %s.",
+				testCase.key,
+				testCase.expected,
+				actual,
+			)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package trie
 
 import (
+	"encoding/gob"
 	"errors"
+	"io"
 	"sort"
 
 	"github.com/Ashish-Bansal/redis-spectacles/pkg/addable"
@@ -13,6 +15,8 @@ import (
 type Edge struct {
 	PrefixCount int
 	Prefix      interface{}
+	// PrefixValue is the sum of values of all the items passing through this edge
+	PrefixValue interface{}
 }
 
 // Node implementing NodeInterface
@@ -20,14 +24,29 @@ type Node struct {
 	Edges     map[*Edge]*Node
 	IsMutable bool
 	DataCount int
+	// DataValue is the sum of values of the items ending at this node
+	DataValue interface{}
+
+	// edgeIndex maps prefixes to the edges, so that child is found without scanning all the edges.
+	// It isn't encoded, Decode rebuilds it.
+	edgeIndex map[interface{}]*Edge
 }
 
 // WalkCallback is a callback for the bfs/dfs functions
 type WalkCallback func(interface{}, int)
 
+// WalkValueCallback is a callback for the dfs function which also receives sum of values of the prefix
+type WalkValueCallback func(interface{}, int, interface{})
+
 // NewNode creates new trie node
 func NewNode() *Node {
-	return &Node{IsMutable: true, Edges: make(map[*Edge]*Node)}
+	return &Node{IsMutable: true, Edges: make(map[*Edge]*Node), edgeIndex: make(map[interface{}]*Edge)}
+}
+
+// addEdge connects the child to the node via the edge.
+func (node *Node) addEdge(edge *Edge, child *Node) {
+	node.Edges[edge] = child
+	node.edgeIndex[edge.Prefix] = edge
 }
 
 // Children returns all direct children for a trie node
@@ -50,6 +69,24 @@ func (node *Node) Count() int {
 	return count
 }
 
+// Value returns sum of values of items passing through this node, nil if no item carried a value.
+// Values must support Addition operation, otherwise it will cause panic.
+func (node *Node) Value() interface{} {
+	value := node.DataValue
+	for edge := range node.Edges {
+		value = mustAdd(value, edge.PrefixValue)
+	}
+	return value
+}
+
+func mustAdd(a interface{}, b interface{}) interface{} {
+	result, err := addable.Add(a, b)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
 // GetEdges returns edges of the node in the sorted order of prefixes
 func (node *Node) GetEdges() []*Edge {
 	edges := node.Edges
@@ -71,6 +108,10 @@ func (node *Node) GetEdges() []*Edge {
 
 // GetEdge returns edge if it exists from current node, otherwise returns nil
 func (node *Node) GetEdge(item interface{}) *Edge {
+	if node.edgeIndex != nil {
+		return node.edgeIndex[item]
+	}
+
 	edges := node.Edges
 	for edge := range edges {
 		if edge.Prefix == item {
@@ -82,6 +123,12 @@ func (node *Node) GetEdge(item interface{}) *Edge {
 
 // Insert adds new element into trie
 func (node *Node) Insert(prefix interface{}) error {
+	return node.InsertWithValue(prefix, nil)
+}
+
+// InsertWithValue adds new element into trie and adds its value to every edge on its path.
+// Value must support Addition operation, otherwise it will cause panic.
+func (node *Node) InsertWithValue(prefix interface{}, value interface{}) error {
 	if !node.IsMutable {
 		return errors.New("Trying to run insert on non-mutable tree instance")
 	}
@@ -101,17 +148,20 @@ func (node *Node) Insert(prefix interface{}) error {
 		edge := currentNode.GetEdge(item)
 		if edge == nil {
 			edge = &Edge{Prefix: item}
-			currentNode.Edges[edge] = NewNode()
+			currentNode.addEdge(edge, NewNode())
 		}
 
 		edge.PrefixCount++
+		edge.PrefixValue = mustAdd(edge.PrefixValue, value)
 		currentNode = currentNode.Edges[edge]
 	}
 	currentNode.DataCount++
+	currentNode.DataValue = mustAdd(currentNode.DataValue, value)
 	return nil
 }
 
 // Condense marks the trie as un-mutable and in case parent has single child, it merges itself with parent node.
+// Node at which some items end is never merged, so that they don't get lost.
 // Prefix interface must support Addition operation, otherwise it will cause panic.
 func (node *Node) Condense() error {
 	if !node.IsMutable {
@@ -121,15 +171,16 @@ func (node *Node) Condense() error {
 	for childEdge, child := range node.Edges {
 		child.Condense()
 		childEdges := child.Edges
-		if len(childEdges) == 1 {
+		if len(childEdges) == 1 && child.DataCount == 0 {
 			for grandChildEdge, grandChildNode := range childEdges {
 				newKey, err := addable.Add(childEdge.Prefix, grandChildEdge.Prefix)
-				newEdge := &Edge{Prefix: newKey, PrefixCount: childEdge.PrefixCount}
+				newEdge := &Edge{Prefix: newKey, PrefixCount: childEdge.PrefixCount, PrefixValue: childEdge.PrefixValue}
 				if err != nil {
 					panic(err)
 				}
 				delete(node.Edges, childEdge)
-				node.Edges[newEdge] = grandChildNode
+				delete(node.edgeIndex, childEdge.Prefix)
+				node.addEdge(newEdge, grandChildNode)
 			}
 		}
 	}
@@ -147,6 +198,20 @@ func (node *Node) dfs(callback WalkCallback, prefix interface{}) {
 	}
 }
 
+func (node *Node) dfsValues(callback WalkValueCallback, prefix interface{}) {
+	for _, edge := range node.GetEdges() {
+		newPrefix, _ := addable.Add(prefix, edge.Prefix)
+		callback(newPrefix, edge.PrefixCount, edge.PrefixValue)
+		childNode := node.Edges[edge]
+		childNode.dfsValues(callback, newPrefix)
+	}
+}
+
+// DFSValues performs depth first search on the trie and calls callback with each prefix, its count and value
+func (node *Node) DFSValues(callback WalkValueCallback) {
+	node.dfsValues(callback, nil)
+}
+
 // DFS performs breadth first search on the trie and calls callback with each node element and prefix till now
 func (node *Node) DFS(callback WalkCallback) {
 	node.dfs(callback, nil)
@@ -178,3 +243,52 @@ func (node *Node) BFS(callback WalkCallback) {
 		}
 	}
 }
+
+// Clone returns deep copy of the trie, which can be condensed or read while the original keeps changing.
+// Values are copied as is, so they must not be modified in place.
+func (node *Node) Clone() *Node {
+	clone := &Node{
+		Edges:     make(map[*Edge]*Node, len(node.Edges)),
+		IsMutable: node.IsMutable,
+		DataCount: node.DataCount,
+		DataValue: node.DataValue,
+		edgeIndex: make(map[interface{}]*Edge, len(node.Edges)),
+	}
+	for edge, child := range node.Edges {
+		edgeClone := *edge
+		clone.addEdge(&edgeClone, child.Clone())
+	}
+	return clone
+}
+
+// Encode writes the trie into writer, so that it can be restored later using Decode.
+// Prefixes of the non-basic types must be registered using gob.Register.
+func (node *Node) Encode(writer io.Writer) error {
+	return gob.NewEncoder(writer).Encode(node)
+}
+
+// Decode reads trie previously written using Encode.
+func Decode(reader io.Reader) (*Node, error) {
+	node := NewNode()
+	err := gob.NewDecoder(reader).Decode(node)
+	if err != nil {
+		return nil, err
+	}
+
+	node.restoreEdges()
+	return node, nil
+}
+
+// restoreEdges initialises edges of nodes which didn't have any, as gob skips empty maps,
+// and rebuilds the edge index.
+func (node *Node) restoreEdges() {
+	if node.Edges == nil {
+		node.Edges = make(map[*Edge]*Node)
+	}
+
+	node.edgeIndex = make(map[interface{}]*Edge, len(node.Edges))
+	for edge, child := range node.Edges {
+		node.edgeIndex[edge.Prefix] = edge
+		child.restoreEdges()
+	}
+}
@@ -1,8 +1,13 @@
 package trie
 
 import (
+	"bytes"
 	"reflect"
+	"strconv"
 	"testing"
+
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/addable"
+	"github.com/Ashish-Bansal/redis-spectacles/pkg/iterator"
 )
 
 func TestTrieCreation(t *testing.T) {
@@ -141,6 +146,10 @@ func TestTrieCondensation(t *testing.T) {
 			{"The", "Bye", "Hello"},
 			{"Bye", "Hello", "The"},
 		},
+		{
+			{"user", "user1"},
+			{"user", "user1"},
+		},
 	}
 
 	for _, testcase := range testcases {
@@ -189,3 +198,225 @@ func TestTrieCountAfterCondensation(t *testing.T) {
 		)
 	}
 }
+
+func TestTrieCondensationKeepsDataCount(t *testing.T) {
+	node := NewNode()
+	node.Insert(iterator.Segments{Str: "user:", Delimiters: ":"})
+	node.Insert(iterator.Segments{Str: "user:1", Delimiters: ":"})
+	node.Insert(iterator.Segments{Str: "user:1:name", Delimiters: ":"})
+	node.Condense()
+
+	keys := make(map[string]int)
+	var walk func(node *Node, prefix string)
+	walk = func(node *Node, prefix string) {
+		if node.DataCount != 0 {
+			keys[prefix] = node.DataCount
+		}
+		for edge, child := range node.Edges {
+			walk(child, prefix+edge.Prefix.(string))
+		}
+	}
+	walk(node, "")
+
+	expectedKeys := map[string]int{"user:": 1, "user:1": 1, "user:1:name": 1}
+	if !reflect.DeepEqual(expectedKeys, keys) {
+		t.Errorf("Keys of condensed trie didn't match. Expected %v, got %v", expectedKeys, keys)
+	}
+}
+
+func TestTrieEncodeDecode(t *testing.T) {
+	node := NewNode()
+	node.Insert("Bag")
+	node.Insert("Bat")
+	node.Insert("Boat")
+
+	var buffer bytes.Buffer
+	err := node.Encode(&buffer)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	decodedNode, err := Decode(&buffer)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	decodedNode.Insert("Bad")
+	if decodedNode.Count() != 4 {
+		t.Errorf(
+			"Key count mismatch. Expected %d, got %d",
+			4,
+			decodedNode.Count(),
+		)
+	}
+
+	expectedPrefixes := []string{"B", "Ba", "Bo", "Bad", "Bag", "Bat", "Boa", "Boat"}
+	prefixes := make([]string, 0)
+	decodedNode.BFS(func(item interface{}, count int) {
+		prefixes = append(prefixes, item.(string))
+	})
+
+	if !reflect.DeepEqual(expectedPrefixes, prefixes) {
+		t.Errorf(
+			"Trie prefix mismatch. Expected %v, got %v",
+			expectedPrefixes,
+			prefixes,
+		)
+	}
+}
+
+// count is a value which adds up like an integer.
+type count int
+
+func (c count) Add(other addable.Addable) (addable.Addable, error) {
+	return c + other.(count), nil
+}
+
+func TestTrieValues(t *testing.T) {
+	node := NewNode()
+	node.InsertWithValue("Bag", count(10))
+	node.InsertWithValue("Bat", count(20))
+	node.InsertWithValue("Boat", count(5))
+	node.Insert("Cat")
+	node.Condense()
+
+	if node.Value() != count(35) {
+		t.Errorf(
+			"Value mismatch. Expected %d, got %v",
+			35,
+			node.Value(),
+		)
+	}
+
+	expectedValues := map[string]interface{}{
+		"B":    count(35),
+		"Ba":   count(30),
+		"Bag":  count(10),
+		"Bat":  count(20),
+		"Boat": count(5),
+		"Cat":  nil,
+	}
+	values := make(map[string]interface{})
+	node.DFSValues(func(item interface{}, count int, value interface{}) {
+		values[item.(string)] = value
+	})
+
+	if !reflect.DeepEqual(expectedValues, values) {
+		t.Errorf(
+			"Trie value mismatch. Expected %v, got %v",
+			expectedValues,
+			values,
+		)
+	}
+}
+
+func TestTrieClone(t *testing.T) {
+	node := NewNode()
+	node.Insert("Bag")
+	node.Insert("Bat")
+
+	clone := node.Clone()
+	clone.Condense()
+	node.Insert("Boat")
+
+	if clone.Count() != 2 {
+		t.Errorf(
+			"Key count mismatch. Expected %d, got %d",
+			2,
+			clone.Count(),
+		)
+	}
+
+	expectedPrefixes := []string{"Ba", "Bag", "Bat"}
+	prefixes := make([]string, 0)
+	clone.DFS(func(item interface{}, count int) {
+		prefixes = append(prefixes, item.(string))
+	})
+
+	if !reflect.DeepEqual(expectedPrefixes, prefixes) {
+		t.Errorf(
+			"Trie prefix mismatch. Expected %v, got %v",
+			expectedPrefixes,
+			prefixes,
+		)
+	}
+
+	if !node.IsMutable || node.Count() != 3 {
+		t.Errorf("Original trie must stay mutable after its clone is condensed")
+	}
+}
+
+func TestTrieSegments(t *testing.T) {
+	node := NewNode()
+	for _, key := range []string{"user:1:name", "user:1:email", "user:2:name", "session:1"} {
+		node.Insert(iterator.Segments{Str: key, Delimiters: ":"})
+	}
+	node.Condense()
+
+	expectedPrefixes := []string{
+		"session:1",
+		"user:",
+		"user:1:",
+		"user:1:email",
+		"user:1:name",
+		"user:2:name",
+	}
+	prefixes := make([]string, 0)
+	node.DFS(func(item interface{}, count int) {
+		prefixes = append(prefixes, item.(string))
+	})
+
+	if !reflect.DeepEqual(expectedPrefixes, prefixes) {
+		t.Errorf(
+			"Trie prefix mismatch. Expected %v, got %v",
+			expectedPrefixes,
+			prefixes,
+		)
+	}
+}
+
+func TestTrieGetEdge(t *testing.T) {
+	node := NewNode()
+	for _, key := range []string{"Bag", "Bat", "Boat"} {
+		node.Insert(key)
+	}
+
+	if edge := node.GetEdge("B"); edge == nil || edge.PrefixCount != 3 {
+		t.Errorf("Edge %q must be found with count %d", "B", 3)
+	}
+	if edge := node.GetEdge("C"); edge != nil {
+		t.Errorf("Edge %q must not be found", "C")
+	}
+
+	clone := node.Clone()
+	if edge := clone.GetEdge("B"); edge == nil || edge == node.GetEdge("B") {
+		t.Error("Clone must find its own copy of the edge")
+	}
+
+	clone.Condense()
+	child := clone.Edges[clone.GetEdge("B")]
+	if child.GetEdge("oat") == nil || child.GetEdge("o") != nil {
+		t.Error("Condensed edge must be found by its merged prefix only")
+	}
+
+	var buffer bytes.Buffer
+	if err := node.Encode(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded.Insert("Bag")
+	if edge := decoded.GetEdge("B"); len(decoded.Edges) != 1 || edge == nil || edge.PrefixCount != 4 {
+		t.Error("Insert into decoded trie must reuse the existing edges")
+	}
+}
+
+func BenchmarkTrieInsertSegments(b *testing.B) {
+	node := NewNode()
+	for i := 0; i < b.N; i++ {
+		node.Insert(iterator.Segments{Str: "user:" + strconv.Itoa(i) + ":cart", Delimiters: ":"})
+	}
+}